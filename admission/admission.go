@@ -0,0 +1,330 @@
+// Package admission provides a framework for validating and mutating admission
+// webhooks for custom resources. It allows expressing rules which can't be
+// captured by an OpenAPI validation schema, like cross-field validation.
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// ValidatePath is the path the Server serves the validating webhook on.
+	ValidatePath = "/validate"
+
+	// MutatePath is the path the Server serves the mutating webhook on.
+	MutatePath = "/mutate"
+)
+
+// ValidateFunc validates an object. On create, old is nil. On delete, obj is
+// nil and old contains the object which is being deleted. When an error is
+// returned, the request is denied with the error message.
+type ValidateFunc func(op admissionv1beta1.Operation, obj, old runtime.Object) error
+
+// MutateFunc modifies the given object before it's persisted. The changes are
+// sent back to the API server as a JSON patch.
+type MutateFunc func(op admissionv1beta1.Operation, obj runtime.Object) error
+
+// mutator is a MutateFunc which also receives the GroupVersionKind of the
+// request.
+type mutator func(gvk schema.GroupVersionKind, op admissionv1beta1.Operation, obj runtime.Object) error
+
+// Server handles AdmissionReview requests for the registered CustomResources.
+// Validating requests are served on ValidatePath, mutating requests on
+// MutatePath.
+// The API server sends requests in the version they were made in, so the
+// handlers of a CustomResource are called for all its served versions.
+type Server struct {
+	mux *http.ServeMux
+
+	mu        sync.RWMutex
+	resources map[schema.GroupKind]*handlers
+}
+
+type handlers struct {
+	// objects holds the Go type of each served version.
+	objects    map[string]runtime.Object
+	validators []ValidateFunc
+	mutators   []mutator
+}
+
+// NewServer returns a new Server without any registered callbacks.
+func NewServer() *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		resources: map[schema.GroupKind]*handlers{},
+	}
+
+	s.mux.HandleFunc(ValidatePath, s.serve(s.validate))
+	s.mux.HandleFunc(MutatePath, s.serve(s.mutate))
+	return s
+}
+
+// RegisterValidator registers a ValidateFunc for the given CustomResource. The
+// objects passed to the function are of the Go type of the version of the
+// request, for a CustomResource without Versions this is its Object.
+func (s *Server) RegisterValidator(cr kubekit.CustomResource, fn ValidateFunc) {
+	h := s.handlers(cr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.validators = append(h.validators, fn)
+}
+
+// RegisterMutator registers a MutateFunc for the given CustomResource. The
+// objects are passed in the same way as for RegisterValidator. Mutators are
+// called in the order they are registered.
+func (s *Server) RegisterMutator(cr kubekit.CustomResource, fn MutateFunc) {
+	s.registerMutator(cr, func(_ schema.GroupVersionKind, op admissionv1beta1.Operation, obj runtime.Object) error {
+		return fn(op, obj)
+	})
+}
+
+// RegisterDefaulter registers a mutator which applies the defaults registered
+// in the given Defaulter for the GroupVersionKind of the request. For a
+// CustomResource with multiple versions, the defaults need to be registered for
+// every served version. Use the same Defaulter for the ResourceHandler to get
+// consistent defaults.
+func (s *Server) RegisterDefaulter(cr kubekit.CustomResource, d *kubekit.Defaulter) {
+	s.registerMutator(cr, func(gvk schema.GroupVersionKind, _ admissionv1beta1.Operation, obj runtime.Object) error {
+		return d.Default(gvk, obj)
+	})
+}
+
+func (s *Server) registerMutator(cr kubekit.CustomResource, fn mutator) {
+	h := s.handlers(cr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.mutators = append(h.mutators, fn)
+}
+
+func (s *Server) handlers(cr kubekit.CustomResource) *handlers {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gk := cr.GroupVersionKind().GroupKind()
+	if _, ok := s.resources[gk]; !ok {
+		s.resources[gk] = &handlers{objects: map[string]runtime.Object{}}
+	}
+
+	h := s.resources[gk]
+	for _, v := range servedVersions(cr) {
+		h.objects[v.Name] = v.Object
+	}
+
+	return h
+}
+
+// servedVersions returns the versions of the CustomResource which are served
+// by the API server. For a CustomResource without Versions, this is its
+// Version.
+func servedVersions(cr kubekit.CustomResource) []kubekit.CustomResourceVersion {
+	if len(cr.Versions) == 0 {
+		return []kubekit.CustomResourceVersion{{Name: cr.GroupVersion().Version, Served: true, Object: cr.Object}}
+	}
+
+	var versions []kubekit.CustomResourceVersion
+	for _, v := range cr.Versions {
+		if v.Served {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions
+}
+
+// ListenAndServeTLS starts an HTTPS server on the given address which serves
+// the admission webhooks. The API server only calls webhooks over HTTPS.
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	srv := &http.Server{Addr: addr, Handler: s}
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type reviewFunc func(*admissionv1beta1.AdmissionRequest) (*admissionv1beta1.AdmissionResponse, error)
+
+func (s *Server) serve(fn reviewFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		review := &admissionv1beta1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode AdmissionReview: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		if review.Request == nil {
+			http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := fn(review.Request)
+		if err != nil {
+			resp = deny(err)
+		}
+		resp.UID = review.Request.UID
+
+		review.Request = nil
+		review.Response = resp
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			kubekit.Logger.Infof("Error encoding AdmissionReview response: %s", err)
+		}
+	}
+}
+
+func (s *Server) validate(req *admissionv1beta1.AdmissionRequest) (*admissionv1beta1.AdmissionResponse, error) {
+	h, into, err := s.lookup(req)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := decode(into, req.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := decode(into, req.OldObject)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	validators := h.validators
+	s.mu.RUnlock()
+
+	for _, fn := range validators {
+		if err := fn(req.Operation, obj, old); err != nil {
+			return nil, err
+		}
+	}
+
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}, nil
+}
+
+func (s *Server) mutate(req *admissionv1beta1.AdmissionRequest) (*admissionv1beta1.AdmissionResponse, error) {
+	h, into, err := s.lookup(req)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := decode(into, req.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	if obj == nil {
+		return &admissionv1beta1.AdmissionResponse{Allowed: true}, nil
+	}
+
+	// compare Go objects rather than the raw request, this way fields which
+	// are unknown to the Go type don't end up being removed. The patch is
+	// applied to the raw request though, so it's created against it.
+	before := obj.DeepCopyObject()
+
+	s.mu.RLock()
+	mutators := h.mutators
+	s.mu.RUnlock()
+
+	gvk := schema.GroupVersionKind{Group: req.Kind.Group, Version: req.Kind.Version, Kind: req.Kind.Kind}
+	for _, fn := range mutators {
+		if err := fn(gvk, req.Operation, obj); err != nil {
+			return nil, err
+		}
+	}
+
+	patch, err := mutationPatch(req.Object.Raw, before, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &patchType,
+	}, nil
+}
+
+// mutationPatch creates the JSON patch for the changes between before and after
+// which can be applied to the raw object.
+func mutationPatch(raw []byte, before, after runtime.Object) ([]byte, error) {
+	var original interface{}
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return nil, err
+	}
+
+	b, err := toJSONValue(before)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := toJSONValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	return createPatch(original, b, a)
+}
+
+// lookup returns the handlers for the kind of the request and the Go type of
+// its version.
+func (s *Server) lookup(req *admissionv1beta1.AdmissionRequest) (*handlers, runtime.Object, error) {
+	gvk := schema.GroupVersionKind{Group: req.Kind.Group, Version: req.Kind.Version, Kind: req.Kind.Kind}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.resources[gvk.GroupKind()]
+	if !ok {
+		return nil, nil, fmt.Errorf("no admission handlers registered for %s", gvk)
+	}
+
+	into, ok := h.objects[gvk.Version]
+	if !ok {
+		return nil, nil, fmt.Errorf("no admission handlers registered for %s", gvk)
+	}
+
+	return h, into, nil
+}
+
+// decode decodes the raw object into a new object of the given type. It
+// returns nil if there is no raw object.
+func decode(into runtime.Object, raw runtime.RawExtension) (runtime.Object, error) {
+	if len(raw.Raw) == 0 {
+		return nil, nil
+	}
+
+	obj := reflect.New(reflect.TypeOf(into).Elem()).Interface().(runtime.Object)
+	return obj, json.Unmarshal(raw.Raw, obj)
+}
+
+func deny(err error) *admissionv1beta1.AdmissionResponse {
+	status := metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+		Reason:  metav1.StatusReasonForbidden,
+		Code:    http.StatusForbidden,
+	}
+
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status = apiStatus.Status()
+	}
+
+	return &admissionv1beta1.AdmissionResponse{Allowed: false, Result: &status}
+}
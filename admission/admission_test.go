@@ -0,0 +1,314 @@
+package admission_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/admission"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              WidgetSpec `json:"spec"`
+}
+
+type WidgetSpec struct {
+	Size     string `json:"size,omitempty"`
+	Replicas int    `json:"replicas,omitempty"`
+}
+
+func (w *Widget) DeepCopyObject() runtime.Object {
+	cp := *w
+	w.ObjectMeta.DeepCopyInto(&cp.ObjectMeta)
+	return &cp
+}
+
+var widgetResource = kubekit.CustomResource{
+	Name:    "widget",
+	Plural:  "widgets",
+	Group:   "kubekit",
+	Version: "v1",
+	Object:  &Widget{},
+}
+
+type WidgetV2 struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              WidgetV2Spec `json:"spec"`
+}
+
+type WidgetV2Spec struct {
+	Size  string `json:"size,omitempty"`
+	Count int    `json:"count,omitempty"`
+}
+
+func (w *WidgetV2) DeepCopyObject() runtime.Object {
+	cp := *w
+	w.ObjectMeta.DeepCopyInto(&cp.ObjectMeta)
+	return &cp
+}
+
+var widgetVersionsResource = kubekit.CustomResource{
+	Name:    "widget",
+	Plural:  "widgets",
+	Group:   "kubekit",
+	Version: "v1",
+	Object:  &Widget{},
+	Versions: []kubekit.CustomResourceVersion{
+		{Name: "v1", Served: true, Storage: true, Object: &Widget{}},
+		{Name: "v2", Served: true, Object: &WidgetV2{}},
+		{Name: "v3", Object: &WidgetV2{}},
+	},
+}
+
+func review(t *testing.T, srv *httptest.Server, path string, op admissionv1beta1.Operation, obj, old *Widget) *admissionv1beta1.AdmissionResponse {
+	var raw, oldRaw []byte
+	if obj != nil {
+		raw, _ = json.Marshal(obj)
+	}
+	if old != nil {
+		oldRaw, _ = json.Marshal(old)
+	}
+
+	return reviewRaw(t, srv, path, "v1", op, raw, oldRaw)
+}
+
+func reviewRaw(t *testing.T, srv *httptest.Server, path, version string, op admissionv1beta1.Operation, raw, oldRaw []byte) *admissionv1beta1.AdmissionResponse {
+	req := &admissionv1beta1.AdmissionReview{
+		Request: &admissionv1beta1.AdmissionRequest{
+			UID:       "1234",
+			Kind:      metav1.GroupVersionKind{Group: "kubekit", Version: version, Kind: "Widget"},
+			Operation: op,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	}
+
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(srv.URL+path, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	result := &admissionv1beta1.AdmissionReview{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatalf("Could not decode response: %s", err)
+	}
+
+	if result.Response == nil {
+		t.Fatalf("Expected a response")
+	}
+
+	if result.Response.UID != "1234" {
+		t.Errorf("Expected UID '1234', got '%s'", result.Response.UID)
+	}
+
+	return result.Response
+}
+
+func TestServer_Validate(t *testing.T) {
+	s := admission.NewServer()
+	s.RegisterValidator(widgetResource, func(op admissionv1beta1.Operation, obj, old runtime.Object) error {
+		if op == admissionv1beta1.Update && old.(*Widget).Spec.Size != obj.(*Widget).Spec.Size {
+			return errors.New("size is immutable")
+		}
+		return nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	small := &Widget{Spec: WidgetSpec{Size: "s"}}
+	large := &Widget{Spec: WidgetSpec{Size: "l"}}
+
+	if resp := review(t, srv, admission.ValidatePath, admissionv1beta1.Create, small, nil); !resp.Allowed {
+		t.Errorf("Expected create to be allowed, got %v", resp.Result)
+	}
+
+	resp := review(t, srv, admission.ValidatePath, admissionv1beta1.Update, large, small)
+	if resp.Allowed {
+		t.Fatalf("Expected update to be denied")
+	}
+
+	if resp.Result.Message != "size is immutable" {
+		t.Errorf("Expected message 'size is immutable', got '%s'", resp.Result.Message)
+	}
+}
+
+func TestServer_Mutate(t *testing.T) {
+	s := admission.NewServer()
+	s.RegisterMutator(widgetResource, func(op admissionv1beta1.Operation, obj runtime.Object) error {
+		w := obj.(*Widget)
+		if w.Spec.Replicas == 0 {
+			w.Spec.Replicas = 1
+		}
+		return nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp := review(t, srv, admission.MutatePath, admissionv1beta1.Create, &Widget{Spec: WidgetSpec{Size: "s"}}, nil)
+	if !resp.Allowed {
+		t.Fatalf("Expected request to be allowed, got %v", resp.Result)
+	}
+
+	if resp.PatchType == nil || *resp.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+		t.Errorf("Expected JSONPatch patch type, got %v", resp.PatchType)
+	}
+
+	if exp := `[{"op":"add","path":"/spec/replicas","value":1}]`; string(resp.Patch) != exp {
+		t.Errorf("Expected patch %s, got %s", exp, resp.Patch)
+	}
+
+	t.Run("applies to the raw object", func(t *testing.T) {
+		raw := []byte(`{"apiVersion":"kubekit/v1","kind":"Widget","metadata":{"name":"foo"},"status":{"ready":true}}`)
+
+		resp := reviewRaw(t, srv, admission.MutatePath, "v1", admissionv1beta1.Create, raw, nil)
+		if !resp.Allowed {
+			t.Fatalf("Expected request to be allowed, got %v", resp.Result)
+		}
+
+		patch, err := jsonpatch.DecodePatch(resp.Patch)
+		if err != nil {
+			t.Fatalf("Could not decode patch %s: %s", resp.Patch, err)
+		}
+
+		patched, err := patch.Apply(raw)
+		if err != nil {
+			t.Fatalf("Could not apply patch %s: %s", resp.Patch, err)
+		}
+
+		exp := `{"apiVersion":"kubekit/v1","kind":"Widget","metadata":{"name":"foo"},"spec":{"replicas":1},"status":{"ready":true}}`
+		if !jsonpatch.Equal(patched, []byte(exp)) {
+			t.Errorf("Expected patched object %s, got %s", exp, patched)
+		}
+	})
+}
+
+func TestServer_Defaulter(t *testing.T) {
+	d := kubekit.NewDefaulter()
+	d.Register(widgetResource, func(obj runtime.Object) {
+		if w := obj.(*Widget); w.Spec.Size == "" {
+			w.Spec.Size = "m"
+		}
+	})
+
+	s := admission.NewServer()
+	s.RegisterDefaulter(widgetResource, d)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp := review(t, srv, admission.MutatePath, admissionv1beta1.Create, &Widget{}, nil)
+	if exp := `[{"op":"add","path":"/spec/size","value":"m"}]`; string(resp.Patch) != exp {
+		t.Errorf("Expected patch %s, got %s", exp, resp.Patch)
+	}
+}
+
+func TestServer_Versions(t *testing.T) {
+	var validated []runtime.Object
+	s := admission.NewServer()
+	s.RegisterValidator(widgetVersionsResource, func(op admissionv1beta1.Operation, obj, old runtime.Object) error {
+		validated = append(validated, obj)
+		return nil
+	})
+	s.RegisterMutator(widgetVersionsResource, func(op admissionv1beta1.Operation, obj runtime.Object) error {
+		if w, ok := obj.(*WidgetV2); ok && w.Spec.Count == 0 {
+			w.Spec.Count = 1
+		}
+		return nil
+	})
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	raw := []byte(`{"apiVersion":"kubekit/v2","kind":"Widget","spec":{"size":"s"}}`)
+	if resp := reviewRaw(t, srv, admission.ValidatePath, "v2", admissionv1beta1.Create, raw, nil); !resp.Allowed {
+		t.Fatalf("Expected v2 request to be allowed, got %v", resp.Result)
+	}
+
+	if resp := review(t, srv, admission.ValidatePath, admissionv1beta1.Create, &Widget{}, nil); !resp.Allowed {
+		t.Fatalf("Expected v1 request to be allowed, got %v", resp.Result)
+	}
+
+	if len(validated) != 2 {
+		t.Fatalf("Expected 2 validated objects, got %d", len(validated))
+	}
+
+	if w, ok := validated[0].(*WidgetV2); !ok || w.Spec.Size != "s" {
+		t.Errorf("Expected the v2 request to be decoded into a WidgetV2, got %#v", validated[0])
+	}
+
+	if _, ok := validated[1].(*Widget); !ok {
+		t.Errorf("Expected the v1 request to be decoded into a Widget, got %#v", validated[1])
+	}
+
+	resp := reviewRaw(t, srv, admission.MutatePath, "v2", admissionv1beta1.Create, raw, nil)
+	if exp := `[{"op":"add","path":"/spec/count","value":1}]`; string(resp.Patch) != exp {
+		t.Errorf("Expected patch %s, got %s", exp, resp.Patch)
+	}
+
+	if resp := reviewRaw(t, srv, admission.ValidatePath, "v3", admissionv1beta1.Create, raw, nil); resp.Allowed {
+		t.Errorf("Expected request for a version which isn't served to be denied")
+	}
+}
+
+func TestServer_UnknownKind(t *testing.T) {
+	srv := httptest.NewServer(admission.NewServer())
+	defer srv.Close()
+
+	if resp := review(t, srv, admission.ValidatePath, admissionv1beta1.Create, &Widget{}, nil); resp.Allowed {
+		t.Errorf("Expected request for an unregistered kind to be denied")
+	}
+}
+
+func TestWebhookConfiguration(t *testing.T) {
+	path := admission.ValidatePath
+	cfg := admissionregv1beta1.WebhookClientConfig{
+		Service: &admissionregv1beta1.ServiceReference{Namespace: "default", Name: "webhooks", Path: &path},
+	}
+
+	vwc := admission.ValidatingWebhookConfiguration("kubekit", cfg, widgetResource)
+	if len(vwc.Webhooks) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(vwc.Webhooks))
+	}
+
+	wh := vwc.Webhooks[0]
+	if wh.Name != "validate.widgets.kubekit" {
+		t.Errorf("Expected name 'validate.widgets.kubekit', got '%s'", wh.Name)
+	}
+
+	rule := wh.Rules[0]
+	if rule.Resources[0] != "widgets" || rule.APIGroups[0] != "kubekit" || rule.APIVersions[0] != "v1" {
+		t.Errorf("Expected rule for kubekit/v1 widgets, got %v", rule.Rule)
+	}
+
+	if len(rule.Operations) != 3 {
+		t.Errorf("Expected 3 operations, got %v", rule.Operations)
+	}
+
+	mwc := admission.MutatingWebhookConfiguration("kubekit", cfg, widgetResource)
+	if ops := mwc.Webhooks[0].Rules[0].Operations; len(ops) != 2 {
+		t.Errorf("Expected mutating webhook for create and update, got %v", ops)
+	}
+
+	vwc = admission.ValidatingWebhookConfiguration("kubekit", cfg, widgetVersionsResource)
+	if versions := vwc.Webhooks[0].Rules[0].APIVersions; !reflect.DeepEqual(versions, []string{"v1", "v2"}) {
+		t.Errorf("Expected rule for the served versions v1 and v2, got %v", versions)
+	}
+}
@@ -0,0 +1,77 @@
+package admission
+
+import (
+	"github.com/jelmersnoeck/kubekit"
+
+	admissionregv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidatingWebhookConfiguration generates the configuration which registers
+// the validating webhook for the given CustomResources with the API server.
+// The client configuration should point to the ValidatePath of the Server.
+func ValidatingWebhookConfiguration(name string, cfg admissionregv1beta1.WebhookClientConfig, crs ...kubekit.CustomResource) *admissionregv1beta1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionregv1beta1.Fail
+	sideEffects := admissionregv1beta1.SideEffectClassNone
+
+	whc := &admissionregv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+
+	for _, cr := range crs {
+		whc.Webhooks = append(whc.Webhooks, admissionregv1beta1.ValidatingWebhook{
+			Name:          "validate." + cr.FullName(),
+			ClientConfig:  cfg,
+			Rules:         rules(cr, admissionregv1beta1.Create, admissionregv1beta1.Update, admissionregv1beta1.Delete),
+			FailurePolicy: &failurePolicy,
+			SideEffects:   &sideEffects,
+		})
+	}
+
+	return whc
+}
+
+// MutatingWebhookConfiguration generates the configuration which registers the
+// mutating webhook for the given CustomResources with the API server. The
+// client configuration should point to the MutatePath of the Server.
+func MutatingWebhookConfiguration(name string, cfg admissionregv1beta1.WebhookClientConfig, crs ...kubekit.CustomResource) *admissionregv1beta1.MutatingWebhookConfiguration {
+	failurePolicy := admissionregv1beta1.Fail
+	sideEffects := admissionregv1beta1.SideEffectClassNone
+
+	whc := &admissionregv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+
+	for _, cr := range crs {
+		whc.Webhooks = append(whc.Webhooks, admissionregv1beta1.MutatingWebhook{
+			Name:          "mutate." + cr.FullName(),
+			ClientConfig:  cfg,
+			Rules:         rules(cr, admissionregv1beta1.Create, admissionregv1beta1.Update),
+			FailurePolicy: &failurePolicy,
+			SideEffects:   &sideEffects,
+		})
+	}
+
+	return whc
+}
+
+// rules matches all served versions of the CustomResource, the match policy
+// defaults to Exact so requests for versions which aren't listed skip the
+// webhook.
+func rules(cr kubekit.CustomResource, ops ...admissionregv1beta1.OperationType) []admissionregv1beta1.RuleWithOperations {
+	var versions []string
+	for _, v := range servedVersions(cr) {
+		versions = append(versions, v.Name)
+	}
+
+	return []admissionregv1beta1.RuleWithOperations{
+		{
+			Operations: ops,
+			Rule: admissionregv1beta1.Rule{
+				APIGroups:   []string{cr.Group},
+				APIVersions: versions,
+				Resources:   []string{cr.GetPlural()},
+			},
+		},
+	}
+}
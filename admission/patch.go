@@ -0,0 +1,118 @@
+package admission
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PatchOperation represents a single JSON patch (RFC 6902) operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// CreatePatch creates a JSON patch which transforms the before object into the
+// after object. Both objects are compared through their JSON representation.
+// Lists which differ are replaced as a whole.
+func CreatePatch(before, after interface{}) ([]byte, error) {
+	b, err := toJSONValue(before)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := toJSONValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	return createPatch(b, b, a)
+}
+
+// createPatch creates a JSON patch which applies the changes between before
+// and after to the original document. The API server applies the patch to the
+// object as it was sent, which can lack objects that before and after have,
+// like a `spec` which isn't set, or contain fields which are unknown to the Go
+// type. Missing objects are added as a whole and unknown fields are kept.
+func createPatch(original, before, after interface{}) ([]byte, error) {
+	ops := diff("", original, before, after)
+	if ops == nil {
+		ops = []PatchOperation{}
+	}
+
+	return json.Marshal(ops)
+}
+
+func toJSONValue(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	return value, json.Unmarshal(data, &value)
+}
+
+func diff(path string, original, before, after interface{}) []PatchOperation {
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	om, ook := original.(map[string]interface{})
+	am, aok := after.(map[string]interface{})
+	if !ook || !aok {
+		// a null value can't be expressed in a replace operation as the value
+		// would be omitted, removing it is equivalent for the API server.
+		if after == nil {
+			return []PatchOperation{{Op: "remove", Path: path}}
+		}
+
+		return []PatchOperation{{Op: "replace", Path: path, Value: after}}
+	}
+
+	bm, _ := before.(map[string]interface{})
+
+	var ops []PatchOperation
+	for _, key := range sortedKeys(bm) {
+		_, inAfter := am[key]
+		_, inOriginal := om[key]
+		if !inAfter && inOriginal {
+			ops = append(ops, PatchOperation{Op: "remove", Path: path + "/" + escape(key)})
+		}
+	}
+
+	for _, key := range sortedKeys(am) {
+		bv, inBefore := bm[key]
+		if inBefore && reflect.DeepEqual(bv, am[key]) {
+			continue
+		}
+
+		p := path + "/" + escape(key)
+		ov, inOriginal := om[key]
+		if !inOriginal {
+			ops = append(ops, PatchOperation{Op: "add", Path: p, Value: am[key]})
+			continue
+		}
+
+		ops = append(ops, diff(p, ov, bv, am[key])...)
+	}
+
+	return ops
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escape escapes a key to be used as a JSON pointer token.
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
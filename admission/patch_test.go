@@ -0,0 +1,60 @@
+package admission_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit/admission"
+)
+
+func TestCreatePatch(t *testing.T) {
+	tcs := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		patch  string
+	}{
+		{
+			name:   "no changes",
+			before: map[string]interface{}{"a": 1},
+			after:  map[string]interface{}{"a": 1},
+			patch:  `[]`,
+		},
+		{
+			name:   "add and remove",
+			before: map[string]interface{}{"a": 1},
+			after:  map[string]interface{}{"b": 2},
+			patch:  `[{"op":"remove","path":"/a"},{"op":"add","path":"/b","value":2}]`,
+		},
+		{
+			name:   "nested replace",
+			before: map[string]interface{}{"spec": map[string]interface{}{"size": "s", "list": []int{1}}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{"size": "m", "list": []int{1, 2}}},
+			patch:  `[{"op":"replace","path":"/spec/list","value":[1,2]},{"op":"replace","path":"/spec/size","value":"m"}]`,
+		},
+		{
+			name:   "escaped keys",
+			before: map[string]interface{}{"labels": map[string]interface{}{}},
+			after:  map[string]interface{}{"labels": map[string]interface{}{"kubekit.io/a~b": "x"}},
+			patch:  `[{"op":"add","path":"/labels/kubekit.io~1a~0b","value":"x"}]`,
+		},
+		{
+			name:   "null value",
+			before: map[string]interface{}{"a": 1},
+			after:  map[string]interface{}{"a": nil},
+			patch:  `[{"op":"remove","path":"/a"}]`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := admission.CreatePatch(tc.before, tc.after)
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			if string(patch) != tc.patch {
+				t.Errorf("Expected patch %s, got %s", tc.patch, patch)
+			}
+		})
+	}
+}
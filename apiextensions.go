@@ -0,0 +1,242 @@
+package kubekit
+
+import (
+	"fmt"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/install"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// CRDVersionV1 installs CRDs through apiextensions.k8s.io/v1, which is
+	// available from Kubernetes 1.16 onwards.
+	CRDVersionV1 = "v1"
+
+	// CRDVersionV1beta1 installs CRDs through apiextensions.k8s.io/v1beta1.
+	CRDVersionV1beta1 = "v1beta1"
+)
+
+var crdScheme = runtime.NewScheme()
+
+func init() {
+	install.Install(crdScheme)
+}
+
+// DefinitionV1 returns the apiextensions.k8s.io/v1 CustomResourceDefinition
+// that is linked to this CustomResource. The v1 API requires a structural
+// schema for every version, when no Validation is specified it's generated
+// from the Go type of the version with GenerateValidation.
+func (c CustomResource) DefinitionV1() (*apiextv1.CustomResourceDefinition, error) {
+	if c.PreserveUnknownFields != nil && *c.PreserveUnknownFields {
+		return nil, fmt.Errorf("PreserveUnknownFields is not supported by apiextensions.k8s.io/v1, use x-kubernetes-preserve-unknown-fields in the schema instead")
+	}
+
+	if c.Validation == nil && len(c.Versions) == 0 {
+		validation, err := GenerateValidation(c.Object)
+		if err != nil {
+			return nil, err
+		}
+		c.Validation = validation
+	}
+
+	if c.Validation == nil {
+		versions := make([]CustomResourceVersion, len(c.Versions))
+		for i, v := range c.Versions {
+			if v.Validation == nil {
+				validation, err := GenerateValidation(v.Object)
+				if err != nil {
+					return nil, err
+				}
+				v.Validation = validation
+			}
+			versions[i] = v
+		}
+		c.Versions = versions
+	}
+
+	return convertToV1(c.Definition())
+}
+
+func convertToV1(in *apiextv1beta1.CustomResourceDefinition) (*apiextv1.CustomResourceDefinition, error) {
+	internal := &apiextensions.CustomResourceDefinition{}
+	if err := crdScheme.Convert(in, internal, nil); err != nil {
+		return nil, err
+	}
+
+	out := &apiextv1.CustomResourceDefinition{}
+	if err := crdScheme.Convert(internal, out, nil); err != nil {
+		return nil, err
+	}
+
+	out.TypeMeta = metav1.TypeMeta{}
+	return out, nil
+}
+
+func convertFromV1(in *apiextv1.CustomResourceDefinition) (*apiextv1beta1.CustomResourceDefinition, error) {
+	internal := &apiextensions.CustomResourceDefinition{}
+	if err := crdScheme.Convert(in, internal, nil); err != nil {
+		return nil, err
+	}
+
+	out := &apiextv1beta1.CustomResourceDefinition{}
+	if err := crdScheme.Convert(internal, out, nil); err != nil {
+		return nil, err
+	}
+
+	out.TypeMeta = metav1.TypeMeta{}
+	return out, nil
+}
+
+// crdClient manages CRDs through either version of the apiextensions API. The
+// CRDs are passed around as v1beta1 objects, the v1 client converts them.
+type crdClient interface {
+	// Definition returns the CRD for the CustomResource as this version of
+	// the API requires it.
+	Definition(c CustomResource) (*apiextv1beta1.CustomResourceDefinition, error)
+
+	Create(crd *apiextv1beta1.CustomResourceDefinition) error
+	Get(name string) (*apiextv1beta1.CustomResourceDefinition, error)
+	Update(crd *apiextv1beta1.CustomResourceDefinition) error
+	Delete(name string, opts *metav1.DeleteOptions) error
+
+	// Diff returns the changes between the CRD in the cluster and the desired
+	// CRD, as seen by this version of the API.
+	Diff(current, desired *apiextv1beta1.CustomResourceDefinition) ([]CRDChange, error)
+}
+
+// newCRDClient returns a crdClient for the given apiextensions version. When
+// no version is given, v1 is used if the API server supports it.
+func newCRDClient(cs clientset.Interface, version string) (crdClient, error) {
+	if version == "" {
+		var err error
+		if version, err = discoverCRDVersion(cs); err != nil {
+			return nil, err
+		}
+	}
+
+	switch version {
+	case CRDVersionV1:
+		return &v1CRDClient{cs: cs}, nil
+	case CRDVersionV1beta1:
+		return &v1beta1CRDClient{cs: cs}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported apiextensions version %s", version)
+}
+
+func discoverCRDVersion(cs clientset.Interface) (string, error) {
+	groups, err := cs.Discovery().ServerGroups()
+	if err != nil {
+		return "", err
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name != apiextv1.GroupName {
+			continue
+		}
+
+		for _, v := range group.Versions {
+			if v.Version == CRDVersionV1 {
+				return CRDVersionV1, nil
+			}
+		}
+	}
+
+	return CRDVersionV1beta1, nil
+}
+
+type v1beta1CRDClient struct {
+	cs clientset.Interface
+}
+
+func (c *v1beta1CRDClient) Definition(cr CustomResource) (*apiextv1beta1.CustomResourceDefinition, error) {
+	return cr.Definition(), nil
+}
+
+func (c *v1beta1CRDClient) Create(crd *apiextv1beta1.CustomResourceDefinition) error {
+	_, err := c.cs.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
+	return err
+}
+
+func (c *v1beta1CRDClient) Get(name string) (*apiextv1beta1.CustomResourceDefinition, error) {
+	return c.cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
+}
+
+func (c *v1beta1CRDClient) Update(crd *apiextv1beta1.CustomResourceDefinition) error {
+	_, err := c.cs.ApiextensionsV1beta1().CustomResourceDefinitions().Update(crd)
+	return err
+}
+
+func (c *v1beta1CRDClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	return c.cs.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(name, opts)
+}
+
+func (c *v1beta1CRDClient) Diff(current, desired *apiextv1beta1.CustomResourceDefinition) ([]CRDChange, error) {
+	return DiffCRD(current, desired)
+}
+
+type v1CRDClient struct {
+	cs clientset.Interface
+}
+
+func (c *v1CRDClient) Definition(cr CustomResource) (*apiextv1beta1.CustomResourceDefinition, error) {
+	crd, err := cr.DefinitionV1()
+	if err != nil {
+		return nil, err
+	}
+
+	return convertFromV1(crd)
+}
+
+func (c *v1CRDClient) Create(crd *apiextv1beta1.CustomResourceDefinition) error {
+	v1crd, err := convertToV1(crd)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.cs.ApiextensionsV1().CustomResourceDefinitions().Create(v1crd)
+	return err
+}
+
+func (c *v1CRDClient) Get(name string) (*apiextv1beta1.CustomResourceDefinition, error) {
+	v1crd, err := c.cs.ApiextensionsV1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertFromV1(v1crd)
+}
+
+func (c *v1CRDClient) Update(crd *apiextv1beta1.CustomResourceDefinition) error {
+	v1crd, err := convertToV1(crd)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.cs.ApiextensionsV1().CustomResourceDefinitions().Update(v1crd)
+	return err
+}
+
+func (c *v1CRDClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	return c.cs.ApiextensionsV1().CustomResourceDefinitions().Delete(name, opts)
+}
+
+func (c *v1CRDClient) Diff(current, desired *apiextv1beta1.CustomResourceDefinition) ([]CRDChange, error) {
+	cur, err := convertToV1(current)
+	if err != nil {
+		return nil, err
+	}
+
+	des, err := convertToV1(desired)
+	if err != nil {
+		return nil, err
+	}
+	apiextv1.SetObjectDefaults_CustomResourceDefinition(des)
+
+	return diffFields("spec", cur.Spec, des.Spec)
+}
@@ -1,6 +1,13 @@
 package kubekit
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -9,8 +16,118 @@ import (
 	"k8s.io/client-go/rest"
 )
 
-// Clientsets returns a set of clientsets for the given configuration.
-func Clientsets(cfg *rest.Config) (kubernetes.Interface, clientset.Interface, error) {
+// ClientOption represents a function that can be used to tune the
+// configuration of the clients.
+type ClientOption func(cfg *rest.Config)
+
+// WithRateLimits sets the maximum queries per second and the burst of queries
+// a client is allowed to send to the API server. Defaults to the client-go
+// defaults of 5 QPS and a burst of 10, which can throttle controllers with
+// large resyncs.
+func WithRateLimits(qps float32, burst int) ClientOption {
+	return func(cfg *rest.Config) {
+		cfg.QPS = qps
+		cfg.Burst = burst
+	}
+}
+
+// WithRequestTimeout sets the maximum duration of a single request, including
+// reading its response. Watch requests are long running and aren't limited, so
+// the same client can be used for Watchers.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return func(cfg *rest.Config) {
+		wrap := cfg.WrapTransport
+		cfg.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			if wrap != nil {
+				rt = wrap(rt)
+			}
+
+			return &timeoutRoundTripper{rt: rt, timeout: d}
+		}
+	}
+}
+
+// timeoutRoundTripper cancels requests which take longer than the timeout,
+// except for watch requests.
+type timeoutRoundTripper struct {
+	rt      http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isWatch(req) {
+		return t.rt.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the timeout also applies to reading the body, it's released once the
+	// body is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func isWatch(req *http.Request) bool {
+	return req.URL.Query().Get("watch") == "true" || strings.Contains(req.URL.Path, "/watch/")
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// WithUserAgent sets a user agent which identifies the controller in the audit
+// logs of the API server. The Patcher provides this option for its name, so
+// requests and applied annotations can be linked.
+func WithUserAgent(name string) ClientOption {
+	return func(cfg *rest.Config) {
+		cfg.UserAgent = UserAgent(name)
+	}
+}
+
+// WithImpersonation performs all requests as the given user and groups. The
+// user of the original configuration needs to be allowed to impersonate them.
+func WithImpersonation(user string, groups ...string) ClientOption {
+	return func(cfg *rest.Config) {
+		cfg.Impersonate = rest.ImpersonationConfig{
+			UserName: user,
+			Groups:   groups,
+		}
+	}
+}
+
+// UserAgent returns the user agent kubekit uses for the given name, like
+// `kubekit-<name> <client-go user agent>`.
+func UserAgent(name string) string {
+	return fmt.Sprintf("kubekit-%s %s", name, rest.DefaultKubernetesUserAgent())
+}
+
+// ConfigWithOptions returns a copy of the given configuration with the options
+// applied.
+func ConfigWithOptions(cfg *rest.Config, opts ...ClientOption) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// Clientsets returns a set of clientsets for the given configuration. The
+// options are applied to a copy of the configuration.
+func Clientsets(cfg *rest.Config, opts ...ClientOption) (kubernetes.Interface, clientset.Interface, error) {
+	cfg = ConfigWithOptions(cfg, opts...)
+
 	kc, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		return nil, nil, err
@@ -25,13 +142,14 @@ func Clientsets(cfg *rest.Config) (kubernetes.Interface, clientset.Interface, er
 }
 
 // InClusterClientsets creates the in cluster configured clientsets with the
-// rest.InClusterConfig.
-func InClusterClientsets() (*rest.Config, kubernetes.Interface, clientset.Interface, error) {
+// rest.InClusterConfig. The returned configuration has the options applied.
+func InClusterClientsets(opts ...ClientOption) (*rest.Config, kubernetes.Interface, clientset.Interface, error) {
 	cfg, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	cfg = ConfigWithOptions(cfg, opts...)
 	kc, ac, err := Clientsets(cfg)
 	if err != nil {
 		return nil, nil, nil, err
@@ -45,8 +163,8 @@ type SchemeBuilder func(*runtime.Scheme) error
 
 // RESTClient configures a new REST Client to be able to understand all the
 // schemes defined. This way users can query objects associated with this
-// scheme.
-func RESTClient(cfg *rest.Config, sgv *schema.GroupVersion, schemeBuilders ...SchemeBuilder) (*rest.RESTClient, error) {
+// scheme. The options are applied to a copy of the configuration.
+func RESTClient(cfg *rest.Config, sgv *schema.GroupVersion, schemeBuilders []SchemeBuilder, opts ...ClientOption) (*rest.RESTClient, error) {
 	scheme := runtime.NewScheme()
 
 	for _, builder := range schemeBuilders {
@@ -55,13 +173,11 @@ func RESTClient(cfg *rest.Config, sgv *schema.GroupVersion, schemeBuilders ...Sc
 		}
 	}
 
-	config := *cfg
+	config := *ConfigWithOptions(cfg, opts...)
 	config.GroupVersion = sgv
 	config.APIPath = "/apis"
 	config.ContentType = runtime.ContentTypeJSON
-	config.NegotiatedSerializer = serializer.DirectCodecFactory{
-		CodecFactory: serializer.NewCodecFactory(scheme),
-	}
+	config.NegotiatedSerializer = serializer.NewCodecFactory(scheme).WithoutConversion()
 
 	return rest.RESTClientFor(&config)
 }
@@ -0,0 +1,121 @@
+package kubekit_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+func TestConfigWithOptions(t *testing.T) {
+	original := &rest.Config{Host: "https://example.com"}
+	cfg := kubekit.ConfigWithOptions(original,
+		kubekit.WithRateLimits(50, 100),
+		kubekit.WithRequestTimeout(10*time.Second),
+	)
+
+	if cfg.QPS != 50 || cfg.Burst != 100 {
+		t.Errorf("Expected QPS 50 and burst 100, got %v and %d", cfg.QPS, cfg.Burst)
+	}
+
+	if cfg.WrapTransport == nil {
+		t.Errorf("Expected the timeout to be applied to the transport")
+	}
+
+	if original.QPS != 0 || original.WrapTransport != nil {
+		t.Errorf("Expected the original configuration not to be modified")
+	}
+}
+
+func TestRESTClient_RequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") != "true" {
+			time.Sleep(200 * time.Millisecond)
+			json.NewEncoder(w).Encode(&Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		json.NewEncoder(w).Encode(&metav1.WatchEvent{
+			Type:   string(watch.Added),
+			Object: runtime.RawExtension{Raw: []byte(`{"kind":"Widget","apiVersion":"kubekit/v1test1","metadata":{"name":"foo"}}`)},
+		})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes},
+		kubekit.WithRequestTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	c := kubekit.NewResourceClient(rc, widgetResource)
+	if _, err := c.Get("default", "foo"); err == nil {
+		t.Errorf("Expected the request to time out")
+	}
+
+	w, err := c.Watch("default", metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error watching, got %s", err)
+	}
+	defer w.Stop()
+
+	select {
+	case ev, ok := <-w.ResultChan():
+		if !ok || ev.Type != watch.Added {
+			t.Errorf("Expected an added event, got %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the watch event")
+	}
+}
+
+func TestClientsets_Options(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	}))
+	defer srv.Close()
+
+	kc, _, err := kubekit.Clientsets(&rest.Config{Host: srv.URL},
+		kubekit.WithUserAgent("test-controller"),
+		kubekit.WithImpersonation("jane", "admins"),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if _, err := kc.CoreV1().Namespaces().Get("default", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if ua := header.Get("User-Agent"); !strings.HasPrefix(ua, "kubekit-test-controller ") {
+		t.Errorf("Expected a kubekit user agent, got '%s'", ua)
+	}
+
+	if u := header.Get("Impersonate-User"); u != "jane" {
+		t.Errorf("Expected to impersonate 'jane', got '%s'", u)
+	}
+
+	if g := header.Get("Impersonate-Group"); g != "admins" {
+		t.Errorf("Expected to impersonate group 'admins', got '%s'", g)
+	}
+}
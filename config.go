@@ -0,0 +1,105 @@
+package kubekit
+
+import (
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ClientConfigOption represents a function that can be used to configure a
+// ClientConfig.
+type ClientConfigOption func(c *ClientConfig)
+
+// WithKubeconfig loads the configuration from the kubeconfig file at the given
+// path, instead of looking it up.
+func WithKubeconfig(path string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.path = path
+	}
+}
+
+// WithContext selects the context of the kubeconfig which is used instead of
+// the current context.
+func WithContext(name string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.context = name
+	}
+}
+
+// WithNamespaceOverride overwrites the namespace of the selected context.
+func WithNamespaceOverride(namespace string) ClientConfigOption {
+	return func(c *ClientConfig) {
+		c.namespace = namespace
+	}
+}
+
+// ClientConfig resolves the configuration to talk to the API server, so
+// controllers can run both inside and outside of a cluster. The configuration
+// is looked up in the following order:
+//
+//  1. the kubeconfig set with WithKubeconfig
+//  2. the kubeconfig files in the KUBECONFIG environment variable, which
+//     are merged
+//  3. ~/.kube/config
+//  4. the in cluster configuration
+type ClientConfig struct {
+	path      string
+	context   string
+	namespace string
+}
+
+// NewClientConfig returns a new ClientConfig.
+func NewClientConfig(opts ...ClientConfigOption) *ClientConfig {
+	c := &ClientConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// RESTConfig returns the resolved configuration with the options applied.
+func (c *ClientConfig) RESTConfig(opts ...ClientOption) (*rest.Config, error) {
+	cfg, err := c.clientConfig().ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return ConfigWithOptions(cfg, opts...), nil
+}
+
+// Namespace returns the namespace of the selected context, or the namespace of
+// the service account when running in cluster. Defaults to `default`.
+func (c *ClientConfig) Namespace() (string, error) {
+	ns, _, err := c.clientConfig().Namespace()
+	return ns, err
+}
+
+// Clientsets creates the clientsets with the resolved configuration. It
+// returns the same values as InClusterClientsets.
+func (c *ClientConfig) Clientsets(opts ...ClientOption) (*rest.Config, kubernetes.Interface, clientset.Interface, error) {
+	cfg, err := c.RESTConfig(opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	kc, ac, err := Clientsets(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return cfg, kc, ac, nil
+}
+
+func (c *ClientConfig) clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.path
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: c.context}
+	overrides.Context.Namespace = c.namespace
+
+	// the deferred loading config falls back to the in cluster config when
+	// there is no kubeconfig.
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
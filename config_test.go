@@ -0,0 +1,117 @@
+package kubekit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+)
+
+const kubeconfigA = `apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: https://a.example.com
+users:
+- name: a
+  user:
+    token: a
+contexts:
+- name: a
+  context:
+    cluster: a
+    user: a
+    namespace: team-a
+current-context: a
+`
+
+const kubeconfigB = `apiVersion: v1
+kind: Config
+clusters:
+- name: b
+  cluster:
+    server: https://b.example.com
+users:
+- name: b
+  user:
+    token: b
+contexts:
+- name: b
+  context:
+    cluster: b
+    user: b
+`
+
+func writeKubeconfig(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Could not write kubeconfig: %s", err)
+	}
+	return path
+}
+
+func TestClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubekit")
+	if err != nil {
+		t.Fatalf("Could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := writeKubeconfig(t, dir, "a", kubeconfigA)
+	b := writeKubeconfig(t, dir, "b", kubeconfigB)
+
+	defer os.Setenv("KUBECONFIG", os.Getenv("KUBECONFIG"))
+	os.Setenv("KUBECONFIG", strings.Join([]string{a, b}, string(os.PathListSeparator)))
+
+	t.Run("current context", func(t *testing.T) {
+		cc := kubekit.NewClientConfig()
+		cfg, err := cc.RESTConfig()
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if cfg.Host != "https://a.example.com" {
+			t.Errorf("Expected host of context a, got '%s'", cfg.Host)
+		}
+
+		if ns, _ := cc.Namespace(); ns != "team-a" {
+			t.Errorf("Expected namespace 'team-a', got '%s'", ns)
+		}
+	})
+
+	t.Run("merged context", func(t *testing.T) {
+		cc := kubekit.NewClientConfig(kubekit.WithContext("b"), kubekit.WithNamespaceOverride("team-b"))
+		cfg, _, _, err := cc.Clientsets()
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if cfg.Host != "https://b.example.com" || cfg.BearerToken != "b" {
+			t.Errorf("Expected config of context b, got host '%s'", cfg.Host)
+		}
+
+		if ns, _ := cc.Namespace(); ns != "team-b" {
+			t.Errorf("Expected namespace 'team-b', got '%s'", ns)
+		}
+	})
+
+	t.Run("explicit path", func(t *testing.T) {
+		cc := kubekit.NewClientConfig(kubekit.WithKubeconfig(b), kubekit.WithContext("b"))
+		cfg, err := cc.RESTConfig()
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if cfg.Host != "https://b.example.com" {
+			t.Errorf("Expected host of context b, got '%s'", cfg.Host)
+		}
+
+		if _, err := kubekit.NewClientConfig(kubekit.WithKubeconfig(filepath.Join(dir, "missing"))).RESTConfig(); err == nil {
+			t.Errorf("Expected an error for a missing kubeconfig")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package kubekit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Result represents the outcome of a Reconcile call. It tells the Controller
+// wether or not the object should be processed again.
+type Result struct {
+	// Requeue tells the Controller to requeue the object with the rate
+	// limited backoff, even if there was no error.
+	Requeue bool
+
+	// RequeueAfter tells the Controller to requeue the object after the given
+	// duration. This takes precedence over Requeue.
+	RequeueAfter time.Duration
+}
+
+// Reconciler is the interface a Controller uses to bring the state of an object
+// to its desired state. The object is identified by its namespace and name,
+// the namespace is empty for cluster scoped resources.
+type Reconciler interface {
+	Reconcile(ctx context.Context, namespace, name string) (Result, error)
+}
+
+// ReconcilerFunc is an adapter to allow ordinary functions to be used as a
+// Reconciler.
+type ReconcilerFunc func(ctx context.Context, namespace, name string) (Result, error)
+
+// Reconcile calls f(ctx, namespace, name).
+func (f ReconcilerFunc) Reconcile(ctx context.Context, namespace, name string) (Result, error) {
+	return f(ctx, namespace, name)
+}
+
+// ControllerOption represents a function that can be used to configure a
+// Controller.
+type ControllerOption func(c *Controller)
+
+// WithWorkers sets the amount of workers which will process items from the
+// queue concurrently. Defaults to `1`.
+func WithWorkers(i int) ControllerOption {
+	return func(c *Controller) {
+		c.workers = i
+	}
+}
+
+// WithRateLimiter sets the rate limiter which is used to requeue objects that
+// errored. Defaults to the client-go DefaultControllerRateLimiter, which
+// combines an exponential per-item backoff with an overall bucket limiter.
+func WithRateLimiter(rl workqueue.RateLimiter) ControllerOption {
+	return func(c *Controller) {
+		c.rateLimiter = rl
+	}
+}
+
+// Controller is a cache.ResourceEventHandler which enqueues the namespace/name
+// key of every object it receives into a rate limited workqueue. The queued
+// keys are then handed to a Reconciler by a configurable set of workers, which
+// means slow handlers no longer block the informer.
+//
+// A Controller is meant to be used as the handler of a Watcher:
+//
+//	c := kubekit.NewController("my-controller", reconciler)
+//	kubekit.NewWatcher(cg, namespace, resource, c).Run(done)
+//	c.Run(done)
+type Controller struct {
+	name        string
+	reconciler  Reconciler
+	workers     int
+	rateLimiter workqueue.RateLimiter
+
+	queue workqueue.RateLimitingInterface
+}
+
+// NewController sets up a new Controller which passes all the objects it
+// receives on to the given Reconciler.
+func NewController(name string, r Reconciler, opts ...ControllerOption) *Controller {
+	c := &Controller{
+		name:        name,
+		reconciler:  r,
+		workers:     1,
+		rateLimiter: workqueue.DefaultControllerRateLimiter(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.queue = workqueue.NewNamedRateLimitingQueue(c.rateLimiter, name)
+	return c
+}
+
+// OnAdd enqueues the added object.
+func (c *Controller) OnAdd(obj interface{}) {
+	c.enqueue(obj)
+}
+
+// OnUpdate enqueues the updated object.
+func (c *Controller) OnUpdate(oldObj, newObj interface{}) {
+	c.enqueue(newObj)
+}
+
+// OnDelete enqueues the deleted object. The Reconciler is responsible for
+// noticing the object no longer exists.
+func (c *Controller) OnDelete(obj interface{}) {
+	c.enqueue(obj)
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	c.queue.Add(key)
+}
+
+// Run starts the workers of the Controller and blocks until the done channel
+// is closed. Once closed, the queue is shut down and Run waits for the workers
+// to finish their current item.
+func (c *Controller) Run(done <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Logger.Infof("Starting controller %s with %d workers", c.name, c.workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait.Until(func() { c.runWorker(ctx) }, time.Second, done)
+		}()
+	}
+
+	<-done
+	Logger.Infof("Shutting down controller %s", c.name)
+
+	cancel()
+	c.queue.ShutDown()
+	wg.Wait()
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	key, ok := item.(string)
+	if !ok {
+		// invalid items will never succeed, there's no need to retry them.
+		c.queue.Forget(item)
+		return true
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		c.queue.Forget(item)
+		utilruntime.HandleError(err)
+		return true
+	}
+
+	res, err := c.reconciler.Reconcile(ctx, namespace, name)
+	switch {
+	case err != nil:
+		Logger.Infof("Error reconciling %s in controller %s: %s", key, c.name, err)
+		c.queue.AddRateLimited(key)
+	case res.RequeueAfter > 0:
+		c.queue.Forget(key)
+		c.queue.AddAfter(key, res.RequeueAfter)
+	case res.Requeue:
+		c.queue.AddRateLimited(key)
+	default:
+		c.queue.Forget(key)
+	}
+
+	return true
+}
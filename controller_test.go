@@ -0,0 +1,118 @@
+package kubekit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (w *Widget) DeepCopyObject() runtime.Object {
+	cp := *w
+	w.ObjectMeta.DeepCopyInto(&cp.ObjectMeta)
+	return &cp
+}
+
+type recorder struct {
+	sync.Mutex
+	calls []string
+	fn    func(int) (kubekit.Result, error)
+	seen  chan string
+}
+
+func (r *recorder) Reconcile(ctx context.Context, namespace, name string) (kubekit.Result, error) {
+	r.Lock()
+	key := namespace + "/" + name
+	r.calls = append(r.calls, key)
+	n := len(r.calls)
+	r.Unlock()
+
+	r.seen <- key
+	if r.fn == nil {
+		return kubekit.Result{}, nil
+	}
+	return r.fn(n)
+}
+
+func waitFor(t *testing.T, seen <-chan string, exp string) {
+	select {
+	case key := <-seen:
+		if key != exp {
+			t.Errorf("Expected key '%s', got '%s'", exp, key)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for '%s' to be reconciled", exp)
+	}
+}
+
+func TestController(t *testing.T) {
+	fastLimiter := kubekit.WithRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond),
+	)
+
+	t.Run("reconciles added objects", func(t *testing.T) {
+		r := &recorder{seen: make(chan string, 10)}
+		c := kubekit.NewController("test", r, kubekit.WithWorkers(2))
+
+		done := make(chan struct{})
+		defer close(done)
+		go c.Run(done)
+
+		c.OnAdd(&Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}})
+		waitFor(t, r.seen, "default/foo")
+	})
+
+	t.Run("requeues on error", func(t *testing.T) {
+		r := &recorder{
+			seen: make(chan string, 10),
+			fn: func(n int) (kubekit.Result, error) {
+				if n == 1 {
+					return kubekit.Result{}, errors.New("failed")
+				}
+				return kubekit.Result{}, nil
+			},
+		}
+		c := kubekit.NewController("test", r, fastLimiter)
+
+		done := make(chan struct{})
+		defer close(done)
+		go c.Run(done)
+
+		c.OnUpdate(nil, &Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}})
+		waitFor(t, r.seen, "default/foo")
+		waitFor(t, r.seen, "default/foo")
+	})
+
+	t.Run("requeues after the given duration", func(t *testing.T) {
+		r := &recorder{
+			seen: make(chan string, 10),
+			fn: func(n int) (kubekit.Result, error) {
+				if n == 1 {
+					return kubekit.Result{RequeueAfter: 10 * time.Millisecond}, nil
+				}
+				return kubekit.Result{}, nil
+			},
+		}
+		c := kubekit.NewController("test", r, fastLimiter)
+
+		done := make(chan struct{})
+		defer close(done)
+		go c.Run(done)
+
+		c.OnDelete(&Widget{ObjectMeta: metav1.ObjectMeta{Name: "bar"}})
+		waitFor(t, r.seen, "/bar")
+		waitFor(t, r.seen, "/bar")
+	})
+}
@@ -0,0 +1,209 @@
+// Package conversion provides a webhook server which converts custom resources
+// between their versions. The API server calls this webhook when an object is
+// requested in a different version than the one it's stored in.
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ConvertFunc converts the in object to the out object. Both objects are of the
+// Go types registered for their versions. The metadata of in is already copied
+// over to out.
+type ConvertFunc func(in, out runtime.Object) error
+
+// Webhook returns the conversion configuration for a CustomResource which uses
+// a conversion webhook with the given client configuration.
+// The API server only allows conversion webhooks for CRDs which prune unknown
+// fields, so every served version needs a validation schema.
+func Webhook(cfg apiextv1beta1.WebhookClientConfig) *apiextv1beta1.CustomResourceConversion {
+	return &apiextv1beta1.CustomResourceConversion{
+		Strategy:                 apiextv1beta1.WebhookConverter,
+		WebhookClientConfig:      &cfg,
+		ConversionReviewVersions: []string{apiextv1beta1.SchemeGroupVersion.Version},
+	}
+}
+
+// Server is an http.Handler which handles ConversionReview requests and
+// dispatches them to the registered ConvertFuncs.
+type Server struct {
+	mu         sync.RWMutex
+	converters map[converterKey]converter
+}
+
+type converterKey struct {
+	group string
+	kind  string
+	from  string
+	to    string
+}
+
+type converter struct {
+	from    runtime.Object
+	to      runtime.Object
+	convert ConvertFunc
+}
+
+// NewServer returns a new Server without any converters.
+func NewServer() *Server {
+	return &Server{converters: map[converterKey]converter{}}
+}
+
+// Register registers a ConvertFunc which converts objects of the given
+// CustomResource from one version to another. Both versions need to be part of
+// the Versions of the CustomResource.
+func (s *Server) Register(cr kubekit.CustomResource, from, to string, fn ConvertFunc) error {
+	fromCR, err := cr.ForVersion(from)
+	if err != nil {
+		return err
+	}
+
+	toCR, err := cr.ForVersion(to)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := converterKey{group: cr.Group, kind: cr.Kind(), from: from, to: to}
+	s.converters[key] = converter{from: fromCR.Object, to: toCR.Object, convert: fn}
+	return nil
+}
+
+// ListenAndServeTLS starts an HTTPS server on the given address which serves
+// the conversion webhook. The API server only calls webhooks over HTTPS.
+func (s *Server) ListenAndServeTLS(addr, certFile, keyFile string) error {
+	srv := &http.Server{Addr: addr, Handler: s}
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextv1beta1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode ConversionReview: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = s.convert(review.Request)
+	review.Request = nil
+	review.TypeMeta = metav1.TypeMeta{
+		APIVersion: apiextv1beta1.SchemeGroupVersion.String(),
+		Kind:       "ConversionReview",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		kubekit.Logger.Infof("Error encoding ConversionReview response: %s", err)
+	}
+}
+
+func (s *Server) convert(req *apiextv1beta1.ConversionRequest) *apiextv1beta1.ConversionResponse {
+	resp := &apiextv1beta1.ConversionResponse{UID: req.UID}
+
+	desired, err := schema.ParseGroupVersion(req.DesiredAPIVersion)
+	if err != nil {
+		resp.Result = failure(err)
+		return resp
+	}
+
+	for _, obj := range req.Objects {
+		converted, err := s.convertObject(obj.Raw, desired)
+		if err != nil {
+			resp.ConvertedObjects = nil
+			resp.Result = failure(err)
+			return resp
+		}
+
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return resp
+}
+
+func (s *Server) convertObject(raw []byte, desired schema.GroupVersion) ([]byte, error) {
+	tm := &metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, tm); err != nil {
+		return nil, err
+	}
+
+	gvk := tm.GroupVersionKind()
+	if gvk.GroupVersion() == desired {
+		return raw, nil
+	}
+
+	s.mu.RLock()
+	c, ok := s.converters[converterKey{group: gvk.Group, kind: gvk.Kind, from: gvk.Version, to: desired.Version}]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no converter registered for %s from %s to %s", gvk.Kind, gvk.GroupVersion(), desired)
+	}
+
+	in := newObject(c.from)
+	if err := json.Unmarshal(raw, in); err != nil {
+		return nil, err
+	}
+
+	out := newObject(c.to)
+	if err := copyMetadata(raw, out); err != nil {
+		return nil, err
+	}
+
+	if err := c.convert(in, out); err != nil {
+		return nil, err
+	}
+
+	out.GetObjectKind().SetGroupVersionKind(desired.WithKind(gvk.Kind))
+	return json.Marshal(out)
+}
+
+// copyMetadata decodes only the metadata of the raw object into obj.
+func copyMetadata(raw []byte, obj runtime.Object) error {
+	partial := struct {
+		Metadata json.RawMessage `json:"metadata,omitempty"`
+	}{}
+	if err := json.Unmarshal(raw, &partial); err != nil {
+		return err
+	}
+
+	if len(partial.Metadata) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(partial)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, obj)
+}
+
+// newObject returns a new, empty object of the same type as obj.
+func newObject(obj runtime.Object) runtime.Object {
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+}
+
+func failure(err error) metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,128 @@
+package conversion_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/conversion"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type v1Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Size              string `json:"size"`
+}
+
+func (w *v1Widget) DeepCopyObject() runtime.Object { return w }
+
+type v2Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Dimensions        string `json:"dimensions"`
+}
+
+func (w *v2Widget) DeepCopyObject() runtime.Object { return w }
+
+var widgetResource = kubekit.CustomResource{
+	Name:   "widget",
+	Group:  "kubekit",
+	Plural: "widgets",
+	Versions: []kubekit.CustomResourceVersion{
+		{Name: "v1", Served: true, Object: &v1Widget{}},
+		{Name: "v2", Served: true, Storage: true, Object: &v2Widget{}},
+	},
+}
+
+func review(t *testing.T, srv *httptest.Server, desired string, objs ...interface{}) *apiextv1beta1.ConversionReview {
+	req := &apiextv1beta1.ConversionReview{
+		Request: &apiextv1beta1.ConversionRequest{UID: "1234", DesiredAPIVersion: desired},
+	}
+	for _, obj := range objs {
+		raw, _ := json.Marshal(obj)
+		req.Request.Objects = append(req.Request.Objects, runtime.RawExtension{Raw: raw})
+	}
+
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(srv.URL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Could not send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	result := &apiextv1beta1.ConversionReview{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatalf("Could not decode response: %s", err)
+	}
+
+	return result
+}
+
+func TestServer(t *testing.T) {
+	s := conversion.NewServer()
+	err := s.Register(widgetResource, "v1", "v2", func(in, out runtime.Object) error {
+		out.(*v2Widget).Dimensions = in.(*v1Widget).Size
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error registering, got %s", err)
+	}
+
+	if err := s.Register(widgetResource, "v1", "v3", nil); err == nil {
+		t.Errorf("Expected an error registering an unknown version")
+	}
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	t.Run("converts objects", func(t *testing.T) {
+		obj := &v1Widget{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "kubekit/v1", Kind: "v1Widget"},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Size:       "large",
+		}
+
+		result := review(t, srv, "kubekit/v2", obj)
+		if result.Response == nil || result.Response.UID != "1234" {
+			t.Fatalf("Expected a response for the request")
+		}
+
+		if result.Response.Result.Status != metav1.StatusSuccess {
+			t.Fatalf("Expected conversion to succeed, got %s", result.Response.Result.Message)
+		}
+
+		converted := &v2Widget{}
+		if err := json.Unmarshal(result.Response.ConvertedObjects[0].Raw, converted); err != nil {
+			t.Fatalf("Could not decode converted object: %s", err)
+		}
+
+		if converted.APIVersion != "kubekit/v2" || converted.Name != "foo" || converted.Dimensions != "large" {
+			t.Errorf("Unexpected converted object: %+v", converted)
+		}
+	})
+
+	t.Run("without converter", func(t *testing.T) {
+		obj := &v2Widget{TypeMeta: metav1.TypeMeta{APIVersion: "kubekit/v2", Kind: "v1Widget"}}
+
+		result := review(t, srv, "kubekit/v1", obj)
+		if result.Response.Result.Status != metav1.StatusFailure {
+			t.Errorf("Expected conversion to fail")
+		}
+	})
+}
+
+func TestWebhook(t *testing.T) {
+	url := "https://kubekit.example.com/convert"
+	conv := conversion.Webhook(apiextv1beta1.WebhookClientConfig{URL: &url})
+
+	if conv.Strategy != apiextv1beta1.WebhookConverter || *conv.WebhookClientConfig.URL != url {
+		t.Errorf("Expected a webhook conversion for %s", url)
+	}
+}
@@ -10,19 +10,161 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 // CustomResource describes the configuration values for a
 // CustomResourceDefinition.
+// A CustomResource can describe multiple versions through Versions. In that
+// case Version and Object represent the version the CustomResource is used
+// with, for example by a Watcher. Use ForVersion to select this version.
+// ListObject is the List type of Object, it's used by NewSchemeBuilder.
 type CustomResource struct {
-	Name       string
-	Plural     string
-	Group      string
-	Version    string
-	Aliases    []string
-	Scope      v1beta1.ResourceScope
+	Name           string
+	Plural         string
+	Singular       string
+	Group          string
+	Version        string
+	Versions       []CustomResourceVersion
+	Aliases        []string
+	Categories     []string
+	Scope          v1beta1.ResourceScope
+	Object         runtime.Object
+	ListObject     runtime.Object
+	Validation     *v1beta1.CustomResourceValidation
+	Subresources   *v1beta1.CustomResourceSubresources
+	Conversion     *v1beta1.CustomResourceConversion
+	PrinterColumns []PrinterColumn
+
+	// PreserveUnknownFields is passed on to apiextensions.k8s.io/v1beta1
+	// CRDs, where it defaults to true. When a conversion webhook is used, it
+	// defaults to false as the API server requires. It's not supported by
+	// apiextensions.k8s.io/v1, use `x-kubernetes-preserve-unknown-fields` in
+	// the schema instead.
+	PreserveUnknownFields *bool
+}
+
+// CustomResourceVersion describes a single version of a CustomResource. Each
+// version has its own Go type, which should have the same type name across all
+// versions.
+type CustomResourceVersion struct {
+	Name string
+
+	// Served specifies wether or not this version is served by the API
+	// server.
+	Served bool
+
+	// Storage specifies wether or not this version is used to persist the
+	// objects. Exactly one version should be the storage version.
+	Storage bool
+
 	Object     runtime.Object
-	Validation *v1beta1.CustomResourceValidation
+	ListObject runtime.Object
+
+	// Validation, Subresources and PrinterColumns overwrite the ones of the
+	// CustomResource for this version.
+	Validation     *v1beta1.CustomResourceValidation
+	Subresources   *v1beta1.CustomResourceSubresources
+	PrinterColumns []PrinterColumn
+}
+
+// PrinterColumn describes an additional column which is shown by `kubectl get`.
+type PrinterColumn struct {
+	Name string
+
+	// Type is the OpenAPI type of the column, one of `integer`, `number`,
+	// `string`, `boolean` or `date`.
+	Type string
+
+	// JSONPath is the path to the value within an object, like
+	// `.spec.replicas`.
+	JSONPath string
+
+	Description string
+
+	// Priority determines when the column is shown. Columns with a priority
+	// higher than 0 are only shown in the wide output.
+	Priority int32
+}
+
+var printerColumnTypes = map[string]bool{
+	"integer": true,
+	"number":  true,
+	"string":  true,
+	"boolean": true,
+	"date":    true,
+}
+
+// ForVersion returns a copy of the CustomResource which uses the given version
+// and its Go type, validation, subresources and printer columns. The other
+// versions keep their own configuration, so the copy describes the same
+// CustomResourceDefinition.
+func (c CustomResource) ForVersion(version string) (CustomResource, error) {
+	for _, v := range c.Versions {
+		if v.Name == version {
+			c.Versions = c.pinVersions()
+			c.Version = v.Name
+			c.Object = v.Object
+			c.ListObject = v.ListObject
+			if v.Validation != nil {
+				c.Validation = v.Validation
+			}
+			if v.Subresources != nil {
+				c.Subresources = v.Subresources
+			}
+			if v.PrinterColumns != nil {
+				c.PrinterColumns = v.PrinterColumns
+			}
+			return c, nil
+		}
+	}
+
+	return c, fmt.Errorf("CustomResource %s has no version %s", c.FullName(), version)
+}
+
+// pinVersions returns a copy of the Versions where the fields which aren't
+// overwritten are set to the ones of the CustomResource, this way they don't
+// change when the top level fields do.
+func (c CustomResource) pinVersions() []CustomResourceVersion {
+	versions := make([]CustomResourceVersion, len(c.Versions))
+	for i, v := range c.Versions {
+		if v.Validation == nil {
+			v.Validation = c.Validation
+		}
+		if v.Subresources == nil {
+			v.Subresources = c.Subresources
+		}
+		if v.PrinterColumns == nil {
+			v.PrinterColumns = c.PrinterColumns
+		}
+		versions[i] = v
+	}
+
+	return versions
+}
+
+// validationFor returns the validation of the given version. This is the
+// validation of the CustomResource, unless the version overwrites it.
+func (c CustomResource) validationFor(version string) *v1beta1.CustomResourceValidation {
+	for _, v := range c.Versions {
+		if v.Name == version && v.Validation != nil {
+			return v.Validation
+		}
+	}
+
+	return c.Validation
+}
+
+// StorageVersion returns the name of the version which is used to persist the
+// objects. When no Versions are specified, this is Version.
+func (c CustomResource) StorageVersion() string {
+	for _, v := range c.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+
+	return c.Version
 }
 
 // GetName returns the CustomResource Name. If no name is specified, the
@@ -50,12 +192,35 @@ func (c CustomResource) FullName() string {
 	return c.GetPlural() + "." + c.Group
 }
 
+// Namespaced returns wether or not the CustomResource is namespace scoped. If
+// no Scope is specified, the resource is namespaced.
+func (c CustomResource) Namespaced() bool {
+	return c.Scope != v1beta1.ClusterScoped
+}
+
+// HasStatusSubresource returns wether or not the /status subresource is
+// enabled for this CustomResource.
+func (c CustomResource) HasStatusSubresource() bool {
+	return c.Subresources != nil && c.Subresources.Status != nil
+}
+
+// HasScaleSubresource returns wether or not the /scale subresource is enabled
+// for this CustomResource.
+func (c CustomResource) HasScaleSubresource() bool {
+	return c.Subresources != nil && c.Subresources.Scale != nil
+}
+
 // GroupVersion returns the GroupVersion Schema representation of this
-// CustomResource.
+// CustomResource. When no Version is specified, the StorageVersion is used.
 func (c CustomResource) GroupVersion() schema.GroupVersion {
+	version := c.Version
+	if version == "" {
+		version = c.StorageVersion()
+	}
+
 	return schema.GroupVersion{
 		Group:   c.Group,
-		Version: c.Version,
+		Version: version,
 	}
 }
 
@@ -65,15 +230,20 @@ func (c CustomResource) GroupVersionKind() schema.GroupVersionKind {
 	return c.GroupVersion().WithKind(c.Kind())
 }
 
-// Kind returns the Type Name of the CustomResource Object.
+// Kind returns the Type Name of the CustomResource Object. When no Object is
+// specified, the Object of the first version is used.
 func (c CustomResource) Kind() string {
+	if c.Object == nil && len(c.Versions) > 0 {
+		return TypeName(c.Versions[0].Object)
+	}
+
 	return TypeName(c.Object)
 }
 
-// Definition returns the CustomResourceDefinition that is linked to this
-// CustomResource.
+// Definition returns the apiextensions.k8s.io/v1beta1 CustomResourceDefinition
+// that is linked to this CustomResource.
 func (c CustomResource) Definition() *apiextv1beta1.CustomResourceDefinition {
-	return &apiextv1beta1.CustomResourceDefinition{
+	crd := &apiextv1beta1.CustomResourceDefinition{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: c.FullName(),
 		},
@@ -83,12 +253,232 @@ func (c CustomResource) Definition() *apiextv1beta1.CustomResourceDefinition {
 			Scope:   c.Scope,
 			Names: apiextv1beta1.CustomResourceDefinitionNames{
 				Plural:     c.Plural,
+				Singular:   c.Singular,
 				ShortNames: c.Aliases,
+				Categories: c.Categories,
 				Kind:       c.Kind(),
 			},
-			Validation: c.Validation,
+			Validation:               c.Validation,
+			Subresources:             c.Subresources,
+			Conversion:               c.Conversion,
+			PreserveUnknownFields:    c.PreserveUnknownFields,
+			AdditionalPrinterColumns: columnDefinitions(c.PrinterColumns),
 		},
 	}
+
+	if c.PreserveUnknownFields == nil && c.hasConversionWebhook() {
+		preserve := false
+		crd.Spec.PreserveUnknownFields = &preserve
+	}
+
+	if len(c.Versions) > 0 {
+		// the API server requires the version to match the first entry of the
+		// versions list.
+		crd.Spec.Version = c.Versions[0].Name
+		for _, v := range c.Versions {
+			version := apiextv1beta1.CustomResourceDefinitionVersion{
+				Name:                     v.Name,
+				Served:                   v.Served,
+				Storage:                  v.Storage,
+				Schema:                   c.Validation,
+				Subresources:             c.Subresources,
+				AdditionalPrinterColumns: crd.Spec.AdditionalPrinterColumns,
+			}
+
+			if v.Validation != nil {
+				version.Schema = v.Validation
+			}
+			if v.Subresources != nil {
+				version.Subresources = v.Subresources
+			}
+			if v.PrinterColumns != nil {
+				version.AdditionalPrinterColumns = columnDefinitions(v.PrinterColumns)
+			}
+
+			crd.Spec.Versions = append(crd.Spec.Versions, version)
+		}
+
+		collapseVersions(&crd.Spec)
+	}
+
+	return crd
+}
+
+func columnDefinitions(columns []PrinterColumn) []apiextv1beta1.CustomResourceColumnDefinition {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	defs := make([]apiextv1beta1.CustomResourceColumnDefinition, len(columns))
+	for i, col := range columns {
+		defs[i] = apiextv1beta1.CustomResourceColumnDefinition{
+			Name:        col.Name,
+			Type:        col.Type,
+			JSONPath:    col.JSONPath,
+			Description: col.Description,
+			Priority:    col.Priority,
+		}
+	}
+
+	return defs
+}
+
+// collapseVersions moves the per version fields to the top level of the spec
+// when they are the same for every version, as the API server doesn't allow
+// identical per version fields.
+func collapseVersions(spec *apiextv1beta1.CustomResourceDefinitionSpec) {
+	first := spec.Versions[0]
+	sameSchema, sameSubresources, sameColumns := true, true, true
+	for _, v := range spec.Versions[1:] {
+		sameSchema = sameSchema && reflect.DeepEqual(v.Schema, first.Schema)
+		sameSubresources = sameSubresources && reflect.DeepEqual(v.Subresources, first.Subresources)
+		sameColumns = sameColumns && reflect.DeepEqual(v.AdditionalPrinterColumns, first.AdditionalPrinterColumns)
+	}
+
+	spec.Validation, spec.Subresources, spec.AdditionalPrinterColumns = nil, nil, nil
+	if sameSchema {
+		spec.Validation = first.Schema
+	}
+	if sameSubresources {
+		spec.Subresources = first.Subresources
+	}
+	if sameColumns {
+		spec.AdditionalPrinterColumns = first.AdditionalPrinterColumns
+	}
+
+	for i := range spec.Versions {
+		if sameSchema {
+			spec.Versions[i].Schema = nil
+		}
+		if sameSubresources {
+			spec.Versions[i].Subresources = nil
+		}
+		if sameColumns {
+			spec.Versions[i].AdditionalPrinterColumns = nil
+		}
+	}
+}
+
+// Validate checks that the configuration of the CustomResource is consistent
+// with the Go type of its Object. The JSON paths of the scale subresource and
+// the printer columns need to exist on this type.
+func (c CustomResource) Validate() error {
+	objects := c.objects()
+	if len(objects) == 0 {
+		return fmt.Errorf("CustomResource %s has no Object", c.FullName())
+	}
+
+	var errs []error
+	if len(c.Versions) > 0 {
+		errs = append(errs, c.validateVersions()...)
+	}
+
+	if c.hasConversionWebhook() && c.PreserveUnknownFields != nil && *c.PreserveUnknownFields {
+		errs = append(errs, fmt.Errorf("a conversion webhook can't be used when PreserveUnknownFields is true"))
+	}
+
+	for _, obj := range objects {
+		errs = append(errs, validateFieldPaths(reflect.TypeOf(obj), c.Subresources, c.PrinterColumns)...)
+	}
+
+	for _, v := range c.Versions {
+		if v.Object != nil {
+			errs = append(errs, validateFieldPaths(reflect.TypeOf(v.Object), v.Subresources, v.PrinterColumns)...)
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c CustomResource) validateVersions() []error {
+	var errs []error
+	names := map[string]bool{}
+	storage := 0
+	for _, v := range c.Versions {
+		if names[v.Name] {
+			errs = append(errs, fmt.Errorf("version %s is specified multiple times", v.Name))
+		}
+		names[v.Name] = true
+
+		if v.Storage {
+			storage++
+		}
+
+		if v.Object == nil {
+			errs = append(errs, fmt.Errorf("version %s has no Object", v.Name))
+		} else if kind := TypeName(v.Object); kind != c.Kind() {
+			errs = append(errs, fmt.Errorf("version %s has kind %s, expected %s", v.Name, kind, c.Kind()))
+		}
+	}
+
+	if storage != 1 {
+		errs = append(errs, fmt.Errorf("expected exactly one storage version, got %d", storage))
+	}
+
+	if c.Version != "" && !names[c.Version] {
+		errs = append(errs, fmt.Errorf("version %s is not part of the versions list", c.Version))
+	}
+
+	return errs
+}
+
+func (c CustomResource) hasConversionWebhook() bool {
+	return c.Conversion != nil && c.Conversion.Strategy == apiextv1beta1.WebhookConverter
+}
+
+// objects returns the distinct Go types of all the versions of the
+// CustomResource.
+func (c CustomResource) objects() []runtime.Object {
+	all := []runtime.Object{c.Object}
+	for _, v := range c.Versions {
+		all = append(all, v.Object)
+	}
+
+	var objects []runtime.Object
+	seen := map[reflect.Type]bool{}
+	for _, obj := range all {
+		if obj == nil || seen[reflect.TypeOf(obj)] {
+			continue
+		}
+
+		seen[reflect.TypeOf(obj)] = true
+		objects = append(objects, obj)
+	}
+
+	return objects
+}
+
+func validateFieldPaths(t reflect.Type, subresources *v1beta1.CustomResourceSubresources, columns []PrinterColumn) []error {
+	var errs []error
+	if subresources != nil && subresources.Scale != nil {
+		scale := subresources.Scale
+		paths := []string{scale.SpecReplicasPath, scale.StatusReplicasPath}
+		if scale.LabelSelectorPath != nil {
+			paths = append(paths, *scale.LabelSelectorPath)
+		}
+
+		for _, path := range paths {
+			if err := validateFieldPath(t, path); err != nil {
+				errs = append(errs, fmt.Errorf("invalid scale subresource: %s", err))
+			}
+		}
+	}
+
+	for _, col := range columns {
+		if col.Name == "" {
+			errs = append(errs, fmt.Errorf("printer column with path %s has no name", col.JSONPath))
+		}
+
+		if !printerColumnTypes[col.Type] {
+			errs = append(errs, fmt.Errorf("printer column %s has invalid type %s", col.Name, col.Type))
+		}
+
+		if err := validateFieldPath(t, col.JSONPath); err != nil {
+			errs = append(errs, fmt.Errorf("invalid printer column %s: %s", col.Name, err))
+		}
+	}
+
+	return errs
 }
 
 // TypeName returns the Type Name of a given object.
@@ -1,10 +1,13 @@
 package kubekit_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/jelmersnoeck/kubekit"
 
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -73,3 +76,357 @@ func TestTypeName(t *testing.T) {
 	}
 
 }
+
+type Pool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PoolSpec   `json:"spec"`
+	Status PoolStatus `json:"status,omitempty"`
+}
+
+type PoolSpec struct {
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+type PoolStatus struct {
+	Replicas   int32             `json:"replicas"`
+	Selector   string            `json:"selector"`
+	Conditions []PoolCondition   `json:"conditions,omitempty"`
+	Extra      map[string]string `json:"extra,omitempty"`
+}
+
+type PoolCondition struct {
+	Type string `json:"type"`
+}
+
+func (p *Pool) DeepCopyObject() runtime.Object { return p }
+
+func TestValidate(t *testing.T) {
+	selector := ".status.selector"
+	invalidSelector := ".status.labelSelector"
+
+	tests := []struct {
+		name  string
+		scale *v1beta1.CustomResourceSubresourceScale
+		valid bool
+	}{
+		{"no scale", nil, true},
+		{
+			"valid paths",
+			&v1beta1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+				LabelSelectorPath:  &selector,
+			},
+			true,
+		},
+		{
+			"invalid selector path",
+			&v1beta1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+				LabelSelectorPath:  &invalidSelector,
+			},
+			false,
+		},
+		{
+			"path without leading dot",
+			&v1beta1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   "spec.replicas",
+				StatusReplicasPath: ".status.replicas",
+			},
+			false,
+		},
+		{
+			"path through a scalar",
+			&v1beta1.CustomResourceSubresourceScale{
+				SpecReplicasPath:   ".spec.replicas.count",
+				StatusReplicasPath: ".status.replicas",
+			},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := kubekit.CustomResource{Object: &Pool{}}
+			if tt.scale != nil {
+				cr.Subresources = &v1beta1.CustomResourceSubresources{Scale: tt.scale}
+			}
+
+			err := cr.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Errorf("Expected an error, got none")
+			}
+		})
+	}
+}
+
+type TestTypeV2 struct{ TestType }
+
+func TestVersions(t *testing.T) {
+	cr := kubekit.CustomResource{
+		Group: "kubekit",
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1test2", Served: true, Storage: true, Object: &TestType{}},
+			{Name: "v1test1", Served: true, Object: &TestType{}},
+		},
+	}
+
+	if err := cr.Validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if v := cr.StorageVersion(); v != "v1test2" {
+		t.Errorf("Expected storage version 'v1test2', got '%s'", v)
+	}
+
+	exp := schema.GroupVersionKind{Group: "kubekit", Version: "v1test2", Kind: "TestType"}
+	if gvk := cr.GroupVersionKind(); gvk != exp {
+		t.Errorf("Expected GVK %v without a Version, got %v", exp, gvk)
+	}
+
+	crd := cr.Definition()
+	if crd.Spec.Version != "v1test2" {
+		t.Errorf("Expected version 'v1test2', got '%s'", crd.Spec.Version)
+	}
+
+	if len(crd.Spec.Versions) != 2 || !crd.Spec.Versions[0].Storage || crd.Spec.Versions[1].Storage {
+		t.Errorf("Expected the versions to be part of the definition")
+	}
+
+	v1, err := cr.ForVersion("v1test1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	exp = schema.GroupVersionKind{Group: "kubekit", Version: "v1test1", Kind: "TestType"}
+	if gvk := v1.GroupVersionKind(); gvk != exp {
+		t.Errorf("Expected GVK %v, got %v", exp, gvk)
+	}
+
+	if _, err := cr.ForVersion("v2"); err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+
+	invalid := cr
+	invalid.Versions = []kubekit.CustomResourceVersion{
+		{Name: "v1test2", Storage: true, Object: &TestType{}},
+		{Name: "v1test1", Storage: true, Object: &TestTypeV2{}},
+	}
+	if err := invalid.Validate(); err == nil {
+		t.Errorf("Expected an error for multiple storage versions and kinds")
+	}
+}
+
+func TestVersionFields(t *testing.T) {
+	v1Schema := &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{Type: "object"}}
+	v2Schema := &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{Type: "object", Required: []string{"spec"}}}
+
+	cr := kubekit.CustomResource{
+		Group: "kubekit",
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1test1", Served: true, Object: &TestType{}, Validation: v1Schema},
+			{Name: "v1test2", Served: true, Storage: true, Object: &TestType{}, Validation: v2Schema},
+		},
+	}
+
+	crd := cr.Definition()
+	if crd.Spec.Validation != nil {
+		t.Errorf("Expected no top level validation for different schemas")
+	}
+
+	if crd.Spec.Versions[0].Schema != v1Schema || crd.Spec.Versions[1].Schema != v2Schema {
+		t.Errorf("Expected the schemas to be set per version")
+	}
+
+	cr.Versions[1].Validation = v1Schema
+	crd = cr.Definition()
+	if crd.Spec.Validation != v1Schema || crd.Spec.Versions[0].Schema != nil {
+		t.Errorf("Expected identical schemas to be set at the top level")
+	}
+
+	v2, err := cr.ForVersion("v1test2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if v2.Validation != v1Schema {
+		t.Errorf("Expected the version schema to be selected")
+	}
+}
+
+func TestForVersionDefinition(t *testing.T) {
+	v1Schema := &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{Type: "object", Required: []string{"spec"}}}
+	topSchema := &v1beta1.CustomResourceValidation{OpenAPIV3Schema: &v1beta1.JSONSchemaProps{Type: "object"}}
+
+	cr := kubekit.CustomResource{
+		Group:      "kubekit",
+		Validation: topSchema,
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1test1", Served: true, Object: &TestType{}, Validation: v1Schema},
+			{Name: "v1test2", Served: true, Storage: true, Object: &TestType{}},
+		},
+	}
+
+	v1, err := cr.ForVersion("v1test1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if v1.Validation != v1Schema {
+		t.Errorf("Expected the version schema to be selected")
+	}
+
+	crd := v1.Definition()
+	if crd.Spec.Versions[0].Schema != v1Schema || crd.Spec.Versions[1].Schema != topSchema {
+		t.Errorf("Expected the other versions to keep their schema")
+	}
+
+	if !reflect.DeepEqual(crd, cr.Definition()) {
+		t.Errorf("Expected the definition of the version to be the same as the original")
+	}
+
+	if cr.Versions[1].Validation != nil {
+		t.Errorf("Expected the original versions not to be modified")
+	}
+}
+
+func TestDefinitionV1(t *testing.T) {
+	cr := kubekit.CustomResource{
+		Group:  "kubekit",
+		Object: &TestType{},
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1test1", Served: true, Object: &TestType{}},
+			{Name: "v1test2", Served: true, Storage: true, Object: &TestType{}},
+		},
+		Subresources: &v1beta1.CustomResourceSubresources{Status: &v1beta1.CustomResourceSubresourceStatus{}},
+	}
+
+	crd, err := cr.DefinitionV1()
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if crd.Spec.PreserveUnknownFields {
+		t.Errorf("Expected unknown fields not to be preserved")
+	}
+
+	if len(crd.Spec.Versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(crd.Spec.Versions))
+	}
+
+	for _, v := range crd.Spec.Versions {
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			t.Errorf("Expected version %s to have a schema", v.Name)
+		}
+
+		if v.Subresources == nil || v.Subresources.Status == nil {
+			t.Errorf("Expected version %s to have the status subresource", v.Name)
+		}
+	}
+
+	preserve := true
+	cr.PreserveUnknownFields = &preserve
+	if _, err := cr.DefinitionV1(); err == nil {
+		t.Errorf("Expected an error when preserving unknown fields")
+	}
+}
+
+func TestConversionWebhook(t *testing.T) {
+	cr := kubekit.CustomResource{
+		Group: "kubekit",
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1test1", Served: true, Object: &TestType{}},
+			{Name: "v1test2", Served: true, Storage: true, Object: &TestType{}},
+		},
+		Conversion: &v1beta1.CustomResourceConversion{Strategy: v1beta1.WebhookConverter},
+	}
+
+	crd := cr.Definition()
+	if p := crd.Spec.PreserveUnknownFields; p == nil || *p {
+		t.Errorf("Expected unknown fields not to be preserved with a conversion webhook, got %v", p)
+	}
+
+	if err := cr.Validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	preserve := true
+	cr.PreserveUnknownFields = &preserve
+	if err := cr.Validate(); err == nil {
+		t.Errorf("Expected an error when preserving unknown fields with a conversion webhook")
+	}
+}
+
+func TestPrinterColumns(t *testing.T) {
+	columns := []kubekit.PrinterColumn{
+		{Name: "Replicas", Type: "integer", JSONPath: ".status.replicas"},
+		{Name: "Ready", Type: "string", JSONPath: `.status.conditions[?(@.type=="Ready")].type`, Priority: 1},
+		{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp", Description: "Time since creation"},
+	}
+
+	cr := kubekit.CustomResource{
+		Group:          "kubekit",
+		Version:        "v1",
+		Object:         &Pool{},
+		Singular:       "pool",
+		Categories:     []string{"all", "kubekit"},
+		PrinterColumns: columns,
+	}
+
+	if err := cr.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	crd := cr.Definition()
+	if crd.Spec.Names.Singular != "pool" {
+		t.Errorf("Expected singular 'pool', got '%s'", crd.Spec.Names.Singular)
+	}
+
+	if len(crd.Spec.Names.Categories) != 2 {
+		t.Errorf("Expected 2 categories, got %v", crd.Spec.Names.Categories)
+	}
+
+	cols := crd.Spec.AdditionalPrinterColumns
+	if len(cols) != 3 {
+		t.Fatalf("Expected 3 printer columns, got %d", len(cols))
+	}
+
+	if cols[1].JSONPath != columns[1].JSONPath || cols[1].Priority != 1 {
+		t.Errorf("Expected column %v, got %v", columns[1], cols[1])
+	}
+
+	invalid := []kubekit.PrinterColumn{
+		{Name: "Size", Type: "string", JSONPath: ".spec.size"},
+		{Name: "Replicas", Type: "int", JSONPath: ".status.replicas"},
+		{Type: "string", JSONPath: ".status.selector"},
+	}
+
+	for _, col := range invalid {
+		cr.PrinterColumns = []kubekit.PrinterColumn{col}
+		if err := cr.Validate(); err == nil {
+			t.Errorf("Expected an error for column %v", col)
+		}
+	}
+
+	cr.PrinterColumns = columns
+	cr.Versions = []kubekit.CustomResourceVersion{
+		{Name: "v1", Served: true, Storage: true, Object: &Pool{}},
+		{Name: "v2", Served: true, Object: &Pool{}, PrinterColumns: columns[:1]},
+	}
+
+	crd = cr.Definition()
+	if crd.Spec.AdditionalPrinterColumns != nil {
+		t.Errorf("Expected no top level printer columns when versions differ")
+	}
+
+	if len(crd.Spec.Versions[0].AdditionalPrinterColumns) != 3 || len(crd.Spec.Versions[1].AdditionalPrinterColumns) != 1 {
+		t.Errorf("Expected the printer columns to be set per version")
+	}
+}
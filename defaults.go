@@ -0,0 +1,202 @@
+package kubekit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaulterFunc sets the default values on the given object. The object is of
+// the type of the registered CustomResource Object.
+type DefaulterFunc func(obj runtime.Object)
+
+// Defaulter is a registry of defaults keyed by the kind of a CustomResource.
+// It applies both the `default` values of the validation schema and Go
+// defaulter functions, so the same defaults can be used by a ResourceHandler
+// and a mutating admission webhook.
+type Defaulter struct {
+	mu       sync.RWMutex
+	defaults map[schema.GroupVersionKind]*resourceDefaults
+}
+
+type resourceDefaults struct {
+	schema *v1beta1.JSONSchemaProps
+	funcs  []DefaulterFunc
+}
+
+// NewDefaulter returns a new Defaulter without any registered defaults.
+func NewDefaulter() *Defaulter {
+	return &Defaulter{defaults: map[schema.GroupVersionKind]*resourceDefaults{}}
+}
+
+// Register registers the defaults for the given CustomResource. The `default`
+// values of its validation schema are applied first, after which the defaulter
+// functions are called in the order they are registered. For a CustomResource
+// with multiple versions, the schema of the version of its GroupVersionKind is
+// used.
+// A schema default is applied when a field is missing or null. Fields which
+// don't have `omitempty` and aren't pointers can't be missing, for those the
+// default is applied when they have their zero value.
+func (d *Defaulter) Register(resource CustomResource, fns ...DefaulterFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	gvk := resource.GroupVersionKind()
+	rd, ok := d.defaults[gvk]
+	if !ok {
+		rd = &resourceDefaults{}
+		d.defaults[gvk] = rd
+	}
+
+	if validation := resource.validationFor(gvk.Version); validation != nil {
+		rd.schema = validation.OpenAPIV3Schema
+	}
+	rd.funcs = append(rd.funcs, fns...)
+}
+
+// Default applies the defaults registered for the given kind to the object.
+// Objects of a kind without registered defaults are left untouched.
+func (d *Defaulter) Default(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	d.mu.RLock()
+	rd, ok := d.defaults[gvk]
+	d.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if rd.schema != nil {
+		if err := applySchemaDefaults(rd.schema, obj); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range rd.funcs {
+		fn(obj)
+	}
+
+	return nil
+}
+
+func applySchemaDefaults(props *v1beta1.JSONSchemaProps, obj runtime.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	changed, err := defaultValue(props, u, reflect.TypeOf(obj))
+	if err != nil || !changed {
+		return err
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u, obj)
+}
+
+// defaultValue sets the defaults of the schema on missing fields of the given
+// value and returns wether or not anything was changed. The Go type of the
+// value is used to find the fields which are always serialized, it can be nil
+// when it's unknown.
+func defaultValue(props *v1beta1.JSONSchemaProps, value interface{}, t reflect.Type) (bool, error) {
+	var changed bool
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, p := range props.Properties {
+			p := p
+			ft, alwaysSet := propertyType(t, name)
+			if p.Default != nil && isUnset(v, name, alwaysSet) {
+				var def interface{}
+				if err := json.Unmarshal(p.Default.Raw, &def); err != nil {
+					return false, err
+				}
+
+				v[name] = def
+				changed = true
+			}
+
+			c, err := defaultValue(&p, v[name], ft)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+
+		if props.AdditionalProperties != nil && props.AdditionalProperties.Schema != nil {
+			for _, item := range v {
+				c, err := defaultValue(props.AdditionalProperties.Schema, item, elemType(t, reflect.Map))
+				if err != nil {
+					return false, err
+				}
+				changed = changed || c
+			}
+		}
+	case []interface{}:
+		if props.Items == nil || props.Items.Schema == nil {
+			break
+		}
+
+		for _, item := range v {
+			c, err := defaultValue(props.Items.Schema, item, elemType(t, reflect.Slice))
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	}
+
+	return changed, nil
+}
+
+// propertyType returns the Go type of the property with the given name and
+// wether or not the field is always serialized, even when it's empty.
+func propertyType(t reflect.Type, name string) (reflect.Type, bool) {
+	if t == nil || derefType(t).Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	field, ok := jsonField(derefType(t), name)
+	if !ok {
+		return nil, false
+	}
+
+	return field.Type, !omitEmpty(field) && field.Type.Kind() != reflect.Ptr
+}
+
+func elemType(t reflect.Type, kind reflect.Kind) reflect.Type {
+	if t == nil || derefType(t).Kind() != kind {
+		return nil
+	}
+
+	return derefType(t).Elem()
+}
+
+// isUnset checks wether or not the field with the given name should get its
+// default value. Fields which are always serialized are unset when they have
+// their zero value.
+func isUnset(v map[string]interface{}, name string, alwaysSet bool) bool {
+	value, ok := v[name]
+	if !ok || value == nil {
+		return true
+	}
+
+	if !alwaysSet {
+		return false
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case int64:
+		return value == 0
+	case float64:
+		return value == 0
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package kubekit_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Database struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              DatabaseSpec `json:"spec"`
+}
+
+type DatabaseSpec struct {
+	Replicas int32          `json:"replicas,omitempty"`
+	Port     int32          `json:"port"`
+	Engine   string         `json:"engine,omitempty"`
+	Users    []DatabaseUser `json:"users,omitempty"`
+}
+
+type DatabaseUser struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+func (d *Database) DeepCopyObject() runtime.Object {
+	cp := *d
+	d.ObjectMeta.DeepCopyInto(&cp.ObjectMeta)
+	cp.Spec.Users = append([]DatabaseUser{}, d.Spec.Users...)
+	return &cp
+}
+
+var databaseResource = kubekit.CustomResource{
+	Name:    "database",
+	Group:   "kubekit",
+	Version: "v1",
+	Object:  &Database{},
+	Validation: &v1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &v1beta1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]v1beta1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]v1beta1.JSONSchemaProps{
+						"engine": {Type: "string", Default: &v1beta1.JSON{Raw: []byte(`"postgres"`)}},
+						"port":   {Type: "integer", Default: &v1beta1.JSON{Raw: []byte(`5432`)}},
+						"users": {
+							Type: "array",
+							Items: &v1beta1.JSONSchemaPropsOrArray{Schema: &v1beta1.JSONSchemaProps{
+								Type: "object",
+								Properties: map[string]v1beta1.JSONSchemaProps{
+									"role": {Type: "string", Default: &v1beta1.JSON{Raw: []byte(`"read"`)}},
+								},
+							}},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestDefaulter(t *testing.T) {
+	d := kubekit.NewDefaulter()
+	d.Register(databaseResource, func(obj runtime.Object) {
+		db := obj.(*Database)
+		if db.Spec.Replicas == 0 {
+			db.Spec.Replicas = 3
+		}
+	})
+
+	db := &Database{Spec: DatabaseSpec{
+		Port:   3306,
+		Engine: "mysql",
+		Users:  []DatabaseUser{{Name: "admin", Role: "write"}, {Name: "app"}},
+	}}
+	if err := d.Default(databaseResource.GroupVersionKind(), db); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if db.Spec.Replicas != 3 {
+		t.Errorf("Expected 3 replicas, got %d", db.Spec.Replicas)
+	}
+
+	if db.Spec.Engine != "mysql" {
+		t.Errorf("Expected engine 'mysql' to be kept, got '%s'", db.Spec.Engine)
+	}
+
+	if db.Spec.Port != 3306 {
+		t.Errorf("Expected port 3306 to be kept, got %d", db.Spec.Port)
+	}
+
+	if r := db.Spec.Users[0].Role; r != "write" {
+		t.Errorf("Expected role 'write' to be kept, got '%s'", r)
+	}
+
+	if r := db.Spec.Users[1].Role; r != "read" {
+		t.Errorf("Expected role 'read' from the schema, got '%s'", r)
+	}
+
+	empty := &Database{}
+	if err := d.Default(databaseResource.GroupVersionKind(), empty); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if empty.Spec.Engine != "postgres" {
+		t.Errorf("Expected engine 'postgres' from the schema, got '%s'", empty.Spec.Engine)
+	}
+
+	// port isn't omitempty, its zero value is serialized but is still unset.
+	if empty.Spec.Port != 5432 {
+		t.Errorf("Expected port 5432 from the schema, got %d", empty.Spec.Port)
+	}
+
+	other := &Widget{}
+	if err := d.Default(widgetResource.GroupVersionKind(), other); err != nil {
+		t.Errorf("Expected no error for a kind without defaults, got %s", err)
+	}
+}
+
+func TestDefaulter_Versions(t *testing.T) {
+	v2Validation := &v1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &v1beta1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]v1beta1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]v1beta1.JSONSchemaProps{
+						"engine": {Type: "string", Default: &v1beta1.JSON{Raw: []byte(`"mysql"`)}},
+					},
+				},
+			},
+		},
+	}
+
+	cr := databaseResource
+	cr.Version = "v2"
+	cr.Versions = []kubekit.CustomResourceVersion{
+		{Name: "v1", Served: true, Object: &Database{}},
+		{Name: "v2", Served: true, Storage: true, Object: &Database{}, Validation: v2Validation},
+	}
+
+	d := kubekit.NewDefaulter()
+	d.Register(cr)
+
+	db := &Database{}
+	if err := d.Default(cr.GroupVersionKind(), db); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if db.Spec.Engine != "mysql" {
+		t.Errorf("Expected engine 'mysql' from the version schema, got '%s'", db.Spec.Engine)
+	}
+
+	v1, err := cr.ForVersion("v1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	d.Register(v1)
+
+	db = &Database{}
+	if err := d.Default(v1.GroupVersionKind(), db); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if db.Spec.Engine != "postgres" {
+		t.Errorf("Expected engine 'postgres' from the top level schema, got '%s'", db.Spec.Engine)
+	}
+}
+
+func TestResourceHandler_Defaulter(t *testing.T) {
+	d := kubekit.NewDefaulter()
+	d.Register(databaseResource)
+
+	var received *Database
+	h := kubekit.NewResourceHandler(&databaseResource, kubekit.ResourceHandlerFuncs{
+		AddFunc: func(obj runtime.Object) { received = obj.(*Database) },
+	}, kubekit.WithDefaulter(d))
+
+	db := &Database{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	h.OnAdd(db)
+
+	if received == nil || received.Spec.Engine != "postgres" {
+		t.Fatalf("Expected the received object to be defaulted, got %v", received)
+	}
+
+	if db.Spec.Engine != "" {
+		t.Errorf("Expected the cached object not to be modified")
+	}
+}
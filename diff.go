@@ -0,0 +1,150 @@
+package kubekit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CRDChangeType describes how a field of a CustomResourceDefinition changed.
+type CRDChangeType string
+
+const (
+	// FieldAdded is used for fields which are not set in the cluster yet.
+	FieldAdded CRDChangeType = "Added"
+
+	// FieldRemoved is used for fields which are set in the cluster but are
+	// unknown to the desired definition.
+	FieldRemoved CRDChangeType = "Removed"
+
+	// FieldModified is used for fields which have a different value.
+	FieldModified CRDChangeType = "Modified"
+)
+
+// CRDChange represents a single change between the CustomResourceDefinition in
+// the cluster and the desired definition. The field is a path like
+// `spec.versions[v1].served`, where list items with a name are referenced by
+// that name.
+type CRDChange struct {
+	Field string
+	Type  CRDChangeType
+}
+
+func (c CRDChange) String() string {
+	return fmt.Sprintf("%s %s", c.Field, c.Type)
+}
+
+// diffFields returns the changes needed to go from the current value to the
+// desired value. Both values are compared through their JSON representation so
+// unset and empty fields are treated the same.
+func diffFields(path string, current, desired interface{}) ([]CRDChange, error) {
+	c, err := toJSONValue(current)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := toJSONValue(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffValues(path, c, d), nil
+}
+
+func toJSONValue(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	return value, json.Unmarshal(data, &value)
+}
+
+func diffValues(path string, current, desired interface{}) []CRDChange {
+	if reflect.DeepEqual(current, desired) {
+		return nil
+	}
+
+	if cm, ok := current.(map[string]interface{}); ok {
+		if dm, ok := desired.(map[string]interface{}); ok {
+			return diffMaps(path, cm, dm)
+		}
+	}
+
+	if cl, ok := namedItems(current); ok {
+		if dl, ok := namedItems(desired); ok {
+			return diffMaps(path, cl, dl)
+		}
+	}
+
+	return []CRDChange{{Field: path, Type: FieldModified}}
+}
+
+func diffMaps(path string, current, desired map[string]interface{}) []CRDChange {
+	keys := map[string]bool{}
+	for k := range current {
+		keys[k] = true
+	}
+	for k := range desired {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []CRDChange
+	for _, k := range sorted {
+		p := joinField(path, k)
+		cv, cok := current[k]
+		dv, dok := desired[k]
+
+		switch {
+		case !cok:
+			changes = append(changes, CRDChange{Field: p, Type: FieldAdded})
+		case !dok:
+			changes = append(changes, CRDChange{Field: p, Type: FieldRemoved})
+		default:
+			changes = append(changes, diffValues(p, cv, dv)...)
+		}
+	}
+
+	return changes
+}
+
+// namedItems converts a list of objects which all have a name, like the
+// versions of a CRD, into a map keyed by `[name]`.
+func namedItems(value interface{}) (map[string]interface{}, bool) {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+
+	items := map[string]interface{}{}
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		items["["+name+"]"] = item
+	}
+
+	return items, true
+}
+
+func joinField(path, key string) string {
+	if key[0] == '[' || path == "" {
+		return path + key
+	}
+
+	return path + "." + key
+}
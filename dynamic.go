@@ -0,0 +1,79 @@
+package kubekit
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// GroupVersionResource returns the GroupVersionResource Schema representation
+// of this CustomResource.
+func (c CustomResource) GroupVersionResource() schema.GroupVersionResource {
+	return c.GroupVersion().WithResource(c.GetPlural())
+}
+
+// ToUnstructured converts an object of the type of the CustomResource Object
+// into an unstructured object. The apiVersion and kind are set from the
+// CustomResource when the object doesn't have them.
+func (c CustomResource) ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	if u.GetObjectKind().GroupVersionKind().Empty() {
+		u.SetGroupVersionKind(c.GroupVersionKind())
+	}
+
+	return u, nil
+}
+
+// FromUnstructured converts an unstructured object into a new object of the
+// type of the CustomResource Object.
+func (c CustomResource) FromUnstructured(u *unstructured.Unstructured) (runtime.Object, error) {
+	obj := newObject(c.Object)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// DynamicResource returns a client for the unstructured objects of the given
+// CustomResource, for when there's no Go type available. The namespace is
+// ignored for cluster scoped resources.
+func DynamicResource(di dynamic.Interface, resource CustomResource, namespace string) dynamic.ResourceInterface {
+	ri := di.Resource(resource.GroupVersionResource())
+	if resource.Namespaced() {
+		return ri.Namespace(namespace)
+	}
+
+	return ri
+}
+
+// DynamicResourceForKind returns a client for the unstructured objects of the
+// given kind. The resource and its scope are discovered through the API. The
+// namespace is ignored for cluster scoped resources.
+func DynamicResourceForKind(di dynamic.Interface, dc discovery.DiscoveryInterface, gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	groups, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := restmapper.NewDiscoveryRESTMapper(groups).RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	ri := di.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return ri.Namespace(namespace), nil
+	}
+
+	return ri, nil
+}
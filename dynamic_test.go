@@ -0,0 +1,76 @@
+package kubekit_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUnstructured(t *testing.T) {
+	widget := &Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+
+	u, err := widgetResource.ToUnstructured(widget)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if u.GetAPIVersion() != "kubekit/v1test1" || u.GetKind() != "Widget" {
+		t.Errorf("Expected apiVersion and kind to be set, got '%s' and '%s'", u.GetAPIVersion(), u.GetKind())
+	}
+
+	obj, err := widgetResource.FromUnstructured(u)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if w, ok := obj.(*Widget); !ok || w.Name != "foo" || w.Namespace != "default" {
+		t.Errorf("Expected Widget default/foo, got %v", obj)
+	}
+}
+
+func TestDynamicResource(t *testing.T) {
+	widget := &Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	u, err := widgetResource.ToUnstructured(widget)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	di := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), u)
+
+	got, err := kubekit.DynamicResource(di, *widgetResource, "default").Get("foo", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if got.GetName() != "foo" {
+		t.Errorf("Expected to get foo, got '%s'", got.GetName())
+	}
+
+	cs := fake.NewSimpleClientset()
+	cs.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "kubekit/v1test1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: true}},
+		},
+	}
+
+	ri, err := kubekit.DynamicResourceForKind(di, cs.Discovery(), widgetResource.GroupVersionKind(), "default")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if _, err := ri.Get("foo", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected to get foo through the discovered resource, got %s", err)
+	}
+
+	gvk := widgetResource.GroupVersionKind()
+	gvk.Kind = "Gadget"
+	if _, err := kubekit.DynamicResourceForKind(di, cs.Discovery(), gvk, "default"); err == nil {
+		t.Errorf("Expected an error for an unknown kind")
+	}
+}
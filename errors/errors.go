@@ -1,6 +1,10 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	// ErrCreateNotAllowed is used for when AllowCreate is disabled and a create
@@ -20,6 +24,30 @@ var (
 
 	// ErrNoPointerObject is used when the passed object is not a pointer.
 	ErrNoPointerObject = errors.New("Given object is not a pointer")
+
+	// ErrLeadershipLost is used when a LeaderElector loses its leadership before
+	// it was asked to stop.
+	ErrLeadershipLost = errors.New("Leadership lost before the elector was stopped")
+
+	// ErrNoStatusSubresource is used when the status of a CustomResource is
+	// written while the status subresource is not enabled.
+	ErrNoStatusSubresource = errors.New("The status subresource is not enabled for this CustomResource")
+
+	// ErrNoScaleSubresource is used when the scale of a CustomResource is read
+	// or written while the scale subresource is not enabled.
+	ErrNoScaleSubresource = errors.New("The scale subresource is not enabled for this CustomResource")
+
+	// ErrCRDNotEstablished is used when a CustomResourceDefinition doesn't get
+	// established within the configured timeout.
+	ErrCRDNotEstablished = errors.New("The CustomResourceDefinition was not established in time")
+
+	// ErrCRDNotDeleted is used when a CustomResourceDefinition isn't removed
+	// within the configured timeout.
+	ErrCRDNotDeleted = errors.New("The CustomResourceDefinition was not deleted in time")
+
+	// ErrOverlappingNamespaces is used when a Watcher is configured to watch
+	// all namespaces as well as specific namespaces.
+	ErrOverlappingNamespaces = errors.New("Watching all namespaces can't be combined with watching specific namespaces")
 )
 
 // IsCreateNotAllowed will return wether or not the provided error equals
@@ -40,6 +68,116 @@ func IsNoObjectGiven(err error) bool {
 	return errEquals(ErrNoObjectGiven, err)
 }
 
+// IsLeadershipLost will return wether or not the provided error equals
+// ErrLeadershipLost.
+func IsLeadershipLost(err error) bool {
+	return errEquals(ErrLeadershipLost, err)
+}
+
+// IsNoStatusSubresource will return wether or not the provided error equals
+// ErrNoStatusSubresource.
+func IsNoStatusSubresource(err error) bool {
+	return errEquals(ErrNoStatusSubresource, err)
+}
+
+// IsNoScaleSubresource will return wether or not the provided error equals
+// ErrNoScaleSubresource.
+func IsNoScaleSubresource(err error) bool {
+	return errEquals(ErrNoScaleSubresource, err)
+}
+
+// IsCRDNotEstablished will return wether or not the provided error equals
+// ErrCRDNotEstablished.
+func IsCRDNotEstablished(err error) bool {
+	return errEquals(ErrCRDNotEstablished, err)
+}
+
+// IsCRDNotDeleted will return wether or not the provided error equals
+// ErrCRDNotDeleted.
+func IsCRDNotDeleted(err error) bool {
+	return errEquals(ErrCRDNotDeleted, err)
+}
+
+// IsOverlappingNamespaces will return wether or not the provided error equals
+// ErrOverlappingNamespaces.
+func IsOverlappingNamespaces(err error) bool {
+	return errEquals(ErrOverlappingNamespaces, err)
+}
+
+// UnexpectedTypeError is used when an object is received which is not of the
+// type that was registered for the CustomResource.
+type UnexpectedTypeError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *UnexpectedTypeError) Error() string {
+	return fmt.Sprintf("Expected object of type %s, got %s", e.Expected, e.Actual)
+}
+
+// IsUnexpectedType will return wether or not the provided error is an
+// UnexpectedTypeError.
+func IsUnexpectedType(err error) bool {
+	_, ok := err.(*UnexpectedTypeError)
+	return ok
+}
+
+// CRDConflictError is used when the CustomResourceDefinition in the cluster has
+// versions or fields which are unknown to the desired definition and would be
+// removed by an update.
+type CRDConflictError struct {
+	Name   string
+	Fields []string
+}
+
+func (e *CRDConflictError) Error() string {
+	return fmt.Sprintf("CustomResourceDefinition %s has fields which are unknown to the desired definition: %s", e.Name, strings.Join(e.Fields, ", "))
+}
+
+// IsCRDConflict will return wether or not the provided error is a
+// CRDConflictError.
+func IsCRDConflict(err error) bool {
+	_, ok := err.(*CRDConflictError)
+	return ok
+}
+
+// CRDInUseError is used when a CustomResourceDefinition isn't deleted because
+// objects of it still exist.
+type CRDInUseError struct {
+	Name string
+}
+
+func (e *CRDInUseError) Error() string {
+	return fmt.Sprintf("CustomResourceDefinition %s still has objects", e.Name)
+}
+
+// IsCRDInUse will return wether or not the provided error is a CRDInUseError.
+func IsCRDInUse(err error) bool {
+	_, ok := err.(*CRDInUseError)
+	return ok
+}
+
+// NamesNotAcceptedError is used when the API server doesn't accept the names
+// of a CustomResourceDefinition, for example because they conflict with the
+// names of another CRD. Reason and Message are taken from the NamesAccepted
+// condition.
+type NamesNotAcceptedError struct {
+	Name    string
+	Reason  string
+	Message string
+}
+
+func (e *NamesNotAcceptedError) Error() string {
+	return fmt.Sprintf("Names of CustomResourceDefinition %s are not accepted: %s: %s", e.Name, e.Reason, e.Message)
+}
+
+// IsNamesNotAccepted will return wether or not the provided error is a
+// NamesNotAcceptedError.
+func IsNamesNotAccepted(err error) bool {
+	_, ok := err.(*NamesNotAcceptedError)
+	return ok
+}
+
 func errEquals(expected, actual error) bool {
 	return expected == actual
 }
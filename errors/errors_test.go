@@ -15,6 +15,12 @@ func TestErrors(t *testing.T) {
 		{errors.IsCreateNotAllowed, errors.ErrCreateNotAllowed},
 		{errors.IsUpdateNotAllowed, errors.ErrUpdateNotAllowed},
 		{errors.IsNoObjectGiven, errors.ErrNoObjectGiven},
+		{errors.IsLeadershipLost, errors.ErrLeadershipLost},
+		{errors.IsNoStatusSubresource, errors.ErrNoStatusSubresource},
+		{errors.IsNoScaleSubresource, errors.ErrNoScaleSubresource},
+		{errors.IsCRDNotEstablished, errors.ErrCRDNotEstablished},
+		{errors.IsCRDNotDeleted, errors.ErrCRDNotDeleted},
+		{errors.IsOverlappingNamespaces, errors.ErrOverlappingNamespaces},
 	}
 
 	for _, err := range errs {
@@ -27,3 +33,47 @@ func TestErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestIsUnexpectedType(t *testing.T) {
+	err := &errors.UnexpectedTypeError{Expected: "*v1.Foo", Actual: "*v1.Bar"}
+	if !errors.IsUnexpectedType(err) {
+		t.Errorf("Expected %T to be an UnexpectedTypeError", err)
+	}
+
+	if errors.IsUnexpectedType(errors.ErrNoObjectGiven) {
+		t.Errorf("Expected %T not to be an UnexpectedTypeError", errors.ErrNoObjectGiven)
+	}
+}
+
+func TestIsCRDConflict(t *testing.T) {
+	err := &errors.CRDConflictError{Name: "foos.kubekit", Fields: []string{"spec.versions[v2]"}}
+	if !errors.IsCRDConflict(err) {
+		t.Errorf("Expected %T to be a CRDConflictError", err)
+	}
+
+	if errors.IsCRDConflict(errors.ErrNoObjectGiven) {
+		t.Errorf("Expected %T not to be a CRDConflictError", errors.ErrNoObjectGiven)
+	}
+}
+
+func TestIsNamesNotAccepted(t *testing.T) {
+	err := &errors.NamesNotAcceptedError{Name: "foos.kubekit", Reason: "ListKindConflict"}
+	if !errors.IsNamesNotAccepted(err) {
+		t.Errorf("Expected %T to be a NamesNotAcceptedError", err)
+	}
+
+	if errors.IsNamesNotAccepted(errors.ErrNoObjectGiven) {
+		t.Errorf("Expected %T not to be a NamesNotAcceptedError", errors.ErrNoObjectGiven)
+	}
+}
+
+func TestIsCRDInUse(t *testing.T) {
+	err := &errors.CRDInUseError{Name: "foos.kubekit"}
+	if !errors.IsCRDInUse(err) {
+		t.Errorf("Expected %T to be a CRDInUseError", err)
+	}
+
+	if errors.IsCRDInUse(errors.ErrNoObjectGiven) {
+		t.Errorf("Expected %T not to be a CRDInUseError", errors.ErrNoObjectGiven)
+	}
+}
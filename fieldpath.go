@@ -0,0 +1,133 @@
+package kubekit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// validateFieldPath checks that the given JSON path, like `.spec.replicas` or
+// `.status.conditions[0].type`, can be resolved on the given type by following
+// the json tags of its fields. Map values are always considered valid, as are
+// the contents of brackets, like filters.
+func validateFieldPath(t reflect.Type, path string) error {
+	if !strings.HasPrefix(path, ".") {
+		return fmt.Errorf("field path %s should start with a '.'", path)
+	}
+
+	current := t
+	for _, segment := range splitFieldPath(strings.TrimPrefix(path, ".")) {
+		name := segment
+		indexed := false
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, indexed = segment[:i], true
+		}
+
+		current = derefType(current)
+		if current.Kind() == reflect.Map {
+			return nil
+		}
+
+		if current.Kind() != reflect.Struct {
+			return fmt.Errorf("field path %s can't be resolved, %s is not an object", path, name)
+		}
+
+		field, ok := jsonField(current, name)
+		if !ok {
+			return fmt.Errorf("field path %s can't be resolved, %s does not exist on %s", path, name, current.Name())
+		}
+		current = field.Type
+
+		if indexed {
+			current = derefType(current)
+			if current.Kind() != reflect.Slice && current.Kind() != reflect.Array {
+				return fmt.Errorf("field path %s can't be resolved, %s is not a list", path, name)
+			}
+			current = current.Elem()
+		}
+	}
+
+	return nil
+}
+
+// splitFieldPath splits a path on the dots which are not within brackets.
+func splitFieldPath(path string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i, r := range path {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(segments, path[start:])
+}
+
+// jsonField looks up the field which is serialized under the given JSON name,
+// taking inlined and embedded structs into account.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		jsonName, inline := jsonTag(field)
+		if jsonName == "-" {
+			continue
+		}
+
+		if inline {
+			if f, ok := jsonField(derefType(field.Type), name); ok {
+				return f, true
+			}
+			continue
+		}
+
+		if jsonName == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// jsonTag returns the JSON name of a field and wether or not the field is
+// inlined into its parent.
+func jsonTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return name, true
+		}
+	}
+
+	if name == "" {
+		if field.Anonymous && derefType(field.Type).Kind() == reflect.Struct {
+			return name, true
+		}
+
+		return field.Name, false
+	}
+
+	return name, false
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,195 @@
+// Package finalizer provides helpers to manage finalizers on custom resources.
+// Finalizers block the deletion of an object until they are removed, which
+// allows a controller to clean up external state before an object is gone,
+// even across controller restarts.
+package finalizer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// Has returns wether or not the object has the given finalizer.
+func Has(obj runtime.Object, name string) (bool, error) {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+
+	return contains(acc.GetFinalizers(), name), nil
+}
+
+// Add adds the finalizer to the object on the server. Only the finalizers of
+// the object are patched, the given object is not modified. The
+// resourceVersion of the object is used as a precondition, when the object
+// has been modified in the meantime a Conflict error is returned.
+// The client should be configured for the GroupVersion of the CustomResource,
+// for example through kubekit.RESTClient.
+func Add(rc rest.Interface, resource *kubekit.CustomResource, obj runtime.Object, name string) error {
+	return update(rc, resource, obj, func(finalizers []string) []string {
+		if contains(finalizers, name) {
+			return nil
+		}
+
+		return append(finalizers, name)
+	})
+}
+
+// Remove removes the finalizer from the object on the server. Like with Add,
+// only the finalizers of the object are patched and the resourceVersion of the
+// object is used as a precondition.
+func Remove(rc rest.Interface, resource *kubekit.CustomResource, obj runtime.Object, name string) error {
+	return update(rc, resource, obj, func(finalizers []string) []string {
+		if !contains(finalizers, name) {
+			return nil
+		}
+
+		result := []string{}
+		for _, f := range finalizers {
+			if f != name {
+				result = append(result, f)
+			}
+		}
+
+		return result
+	})
+}
+
+// update sends a JSON merge patch with the finalizers returned by fn. When fn
+// returns nil, the finalizers don't need to change and nothing is sent.
+func update(rc rest.Interface, resource *kubekit.CustomResource, obj runtime.Object, fn func([]string) []string) error {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	finalizers := fn(acc.GetFinalizers())
+	if finalizers == nil {
+		return nil
+	}
+
+	patch, err := finalizersPatch(finalizers, acc.GetResourceVersion())
+	if err != nil {
+		return err
+	}
+
+	return rc.Patch(types.MergePatchType).
+		NamespaceIfScoped(acc.GetNamespace(), resource.Namespaced()).
+		Resource(resource.GetPlural()).
+		Name(acc.GetName()).
+		Body(patch).
+		Do().
+		Error()
+}
+
+// finalizersPatch creates a JSON merge patch which replaces the finalizers. A
+// merge patch replaces lists as a whole, the resourceVersion makes sure this
+// doesn't overwrite finalizers which have been changed in the meantime.
+func finalizersPatch(finalizers []string, resourceVersion string) ([]byte, error) {
+	metadata := map[string]interface{}{"finalizers": finalizers}
+	if resourceVersion != "" {
+		metadata["resourceVersion"] = resourceVersion
+	}
+
+	return json.Marshal(map[string]interface{}{"metadata": metadata})
+}
+
+func contains(finalizers []string, name string) bool {
+	for _, f := range finalizers {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Finalizer cleans up the state associated with an object which is being
+// deleted.
+type Finalizer interface {
+	Finalize(ctx context.Context, obj runtime.Object) error
+}
+
+// FinalizerFunc is an adapter to allow ordinary functions to be used as a
+// Finalizer.
+type FinalizerFunc func(ctx context.Context, obj runtime.Object) error
+
+// Finalize calls f(ctx, obj).
+func (f FinalizerFunc) Finalize(ctx context.Context, obj runtime.Object) error {
+	return f(ctx, obj)
+}
+
+// Reconciler is a kubekit.Reconciler which manages a finalizer for the objects
+// it reconciles. Objects without the finalizer get it added before they are
+// passed on to the next Reconciler. Objects which are being deleted are passed
+// to the Finalizer instead, and the finalizer is only removed once the
+// Finalizer succeeds.
+type Reconciler struct {
+	name      string
+	resource  *kubekit.CustomResource
+	client    rest.Interface
+	lister    *kubekit.Lister
+	finalizer Finalizer
+	next      kubekit.Reconciler
+}
+
+// NewReconciler returns a new Reconciler which manages the finalizer with the
+// given name. Objects are read from the given Lister, usually the Lister of the
+// Watcher feeding the Controller, and patched through the given client.
+func NewReconciler(name string, resource *kubekit.CustomResource, rc rest.Interface, l *kubekit.Lister, f Finalizer, next kubekit.Reconciler) *Reconciler {
+	return &Reconciler{
+		name:      name,
+		resource:  resource,
+		client:    rc,
+		lister:    l,
+		finalizer: f,
+		next:      next,
+	}
+}
+
+// Reconcile implements kubekit.Reconciler.
+func (r *Reconciler) Reconcile(ctx context.Context, namespace, name string) (kubekit.Result, error) {
+	obj, err := r.lister.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return r.next.Reconcile(ctx, namespace, name)
+	} else if err != nil {
+		return kubekit.Result{}, err
+	}
+
+	// objects in the cache are shared, copy it before we hand it out.
+	obj = obj.DeepCopyObject()
+	obj.GetObjectKind().SetGroupVersionKind(r.resource.GroupVersionKind())
+
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return kubekit.Result{}, err
+	}
+
+	if acc.GetDeletionTimestamp() != nil {
+		if !contains(acc.GetFinalizers(), r.name) {
+			return kubekit.Result{}, nil
+		}
+
+		if err := r.finalizer.Finalize(ctx, obj); err != nil {
+			return kubekit.Result{}, err
+		}
+
+		return kubekit.Result{}, Remove(r.client, r.resource, obj, r.name)
+	}
+
+	if !contains(acc.GetFinalizers(), r.name) {
+		if err := Add(r.client, r.resource, obj, r.name); err != nil {
+			return kubekit.Result{}, err
+		}
+	}
+
+	return r.next.Reconcile(ctx, namespace, name)
+}
@@ -0,0 +1,248 @@
+package finalizer_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/finalizer"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestHas(t *testing.T) {
+	obj := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"kubekit.io/foo"}},
+	}
+
+	tests := []struct {
+		name string
+		exp  bool
+	}{
+		{"kubekit.io/foo", true},
+		{"kubekit.io/bar", false},
+	}
+
+	for _, tt := range tests {
+		has, err := finalizer.Has(obj, tt.name)
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if has != tt.exp {
+			t.Errorf("Expected Has(%s) to be %t, got %t", tt.name, tt.exp, has)
+		}
+	}
+}
+
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (w *Widget) DeepCopyObject() runtime.Object {
+	c := *w
+	w.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+var widgetResource = &kubekit.CustomResource{
+	Name:    "widget",
+	Plural:  "widgets",
+	Group:   "kubekit",
+	Version: "v1test1",
+	Object:  &Widget{},
+}
+
+type patchRequest struct {
+	path        string
+	contentType string
+	body        string
+}
+
+func patchServer(t *testing.T) (rest.Interface, *[]patchRequest, func()) {
+	var requests []patchRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, patchRequest{
+			path:        r.Method + " " + r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+
+	gv := widgetResource.GroupVersion()
+	addTypes := func(s *runtime.Scheme) error {
+		s.AddKnownTypes(gv, &Widget{})
+		return nil
+	}
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &gv, []kubekit.SchemeBuilder{addTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	return rc, &requests, srv.Close
+}
+
+func widget(finalizers ...string) *Widget {
+	return &Widget{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "foo",
+		ResourceVersion: "42",
+		Finalizers:      finalizers,
+	}}
+}
+
+func TestAddRemove(t *testing.T) {
+	tests := []struct {
+		name  string
+		fn    func(rest.Interface, *kubekit.CustomResource, runtime.Object, string) error
+		obj   *Widget
+		patch string
+	}{
+		{"add", finalizer.Add, widget("other"), `{"metadata":{"finalizers":["other","kubekit.io/foo"],"resourceVersion":"42"}}`},
+		{"add existing", finalizer.Add, widget("kubekit.io/foo"), ""},
+		{"remove", finalizer.Remove, widget("kubekit.io/foo", "other"), `{"metadata":{"finalizers":["other"],"resourceVersion":"42"}}`},
+		{"remove last", finalizer.Remove, widget("kubekit.io/foo"), `{"metadata":{"finalizers":[],"resourceVersion":"42"}}`},
+		{"remove missing", finalizer.Remove, widget("other"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, requests, closeFn := patchServer(t)
+			defer closeFn()
+			orig := tt.obj.DeepCopyObject()
+
+			if err := tt.fn(rc, widgetResource, tt.obj, "kubekit.io/foo"); err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			if !reflect.DeepEqual(orig, tt.obj) {
+				t.Errorf("Expected the given object not to be modified")
+			}
+
+			if tt.patch == "" {
+				if len(*requests) != 0 {
+					t.Errorf("Expected no requests, got %v", *requests)
+				}
+				return
+			}
+
+			if len(*requests) != 1 {
+				t.Fatalf("Expected 1 request, got %v", *requests)
+			}
+
+			req := (*requests)[0]
+			if exp := "PATCH /apis/kubekit/v1test1/namespaces/default/widgets/foo"; req.path != exp {
+				t.Errorf("Expected request %s, got %s", exp, req.path)
+			}
+
+			if req.contentType != string(types.MergePatchType) {
+				t.Errorf("Expected a merge patch, got %s", req.contentType)
+			}
+
+			if req.body != tt.patch {
+				t.Errorf("Expected patch %s, got %s", tt.patch, req.body)
+			}
+		})
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	deleted := metav1.Now()
+
+	tests := []struct {
+		name      string
+		obj       *Widget
+		deleted   bool
+		finalized bool
+		next      bool
+		patch     string
+	}{
+		{"adds the finalizer", widget(), false, false, true, `{"metadata":{"finalizers":["kubekit.io/foo"],"resourceVersion":"42"}}`},
+		{"passes on objects with the finalizer", widget("kubekit.io/foo"), false, false, true, ""},
+		{"finalizes deleted objects", widget("kubekit.io/foo"), true, true, false, `{"metadata":{"finalizers":[],"resourceVersion":"42"}}`},
+		{"ignores deleted objects without the finalizer", widget("other"), true, false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.deleted {
+				tt.obj.DeletionTimestamp = &deleted
+			}
+
+			rc, requests, closeFn := patchServer(t)
+			defer closeFn()
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			indexer.Add(tt.obj)
+
+			var finalized, next bool
+			f := finalizer.FinalizerFunc(func(ctx context.Context, obj runtime.Object) error {
+				finalized = true
+				return nil
+			})
+			n := kubekit.ReconcilerFunc(func(ctx context.Context, namespace, name string) (kubekit.Result, error) {
+				next = true
+				return kubekit.Result{}, nil
+			})
+
+			r := finalizer.NewReconciler("kubekit.io/foo", widgetResource, rc, kubekit.NewLister(indexer, widgetResource), f, n)
+			if _, err := r.Reconcile(context.Background(), "default", "foo"); err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			if finalized != tt.finalized {
+				t.Errorf("Expected finalized to be %t, got %t", tt.finalized, finalized)
+			}
+
+			if next != tt.next {
+				t.Errorf("Expected the next Reconciler to be called to be %t, got %t", tt.next, next)
+			}
+
+			var patches []string
+			for _, req := range *requests {
+				patches = append(patches, req.body)
+			}
+			if (tt.patch == "" && len(patches) != 0) || (tt.patch != "" && !reflect.DeepEqual(patches, []string{tt.patch})) {
+				t.Errorf("Expected patch %q, got %v", tt.patch, patches)
+			}
+		})
+	}
+
+	t.Run("finalizer error", func(t *testing.T) {
+		obj := widget("kubekit.io/foo")
+		obj.DeletionTimestamp = &deleted
+
+		rc, requests, closeFn := patchServer(t)
+		defer closeFn()
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(obj)
+
+		f := finalizer.FinalizerFunc(func(ctx context.Context, obj runtime.Object) error {
+			return errors.New("cleanup failed")
+		})
+
+		r := finalizer.NewReconciler("kubekit.io/foo", widgetResource, rc, kubekit.NewLister(indexer, widgetResource), f, nil)
+		if _, err := r.Reconcile(context.Background(), "default", "foo"); err == nil {
+			t.Errorf("Expected the error of the Finalizer to be returned")
+		}
+
+		if len(*requests) != 0 {
+			t.Errorf("Expected the finalizer not to be removed, got %v", *requests)
+		}
+	})
+}
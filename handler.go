@@ -0,0 +1,167 @@
+package kubekit
+
+import (
+	"fmt"
+	"reflect"
+
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ResourceHandlerFuncs represents the typed callbacks a ResourceHandler will
+// invoke. Every object passed into these functions is a deep copy of the object
+// in the cache and is of the same type as the registered CustomResource Object,
+// so it's safe to type assert and modify them.
+type ResourceHandlerFuncs struct {
+	AddFunc    func(obj runtime.Object)
+	UpdateFunc func(oldObj, newObj runtime.Object)
+	DeleteFunc func(obj runtime.Object)
+
+	// ErrorFunc is called when an object can't be handed to the typed
+	// callbacks, for example when the object is not of the registered type.
+	// Defaults to logging the error through the kubekit Logger.
+	ErrorFunc func(err error)
+}
+
+// ResourceHandler is a cache.ResourceEventHandler which converts the received
+// objects into the type of a CustomResource Object before handing them to the
+// configured ResourceHandlerFuncs.
+type ResourceHandler struct {
+	resource  *CustomResource
+	funcs     ResourceHandlerFuncs
+	defaulter *Defaulter
+}
+
+// ResourceHandlerOption represents a function that can be used to configure a
+// ResourceHandler.
+type ResourceHandlerOption func(h *ResourceHandler)
+
+// WithDefaulter applies the defaults registered in the given Defaulter to every
+// object before it is handed to the ResourceHandlerFuncs.
+func WithDefaulter(d *Defaulter) ResourceHandlerOption {
+	return func(h *ResourceHandler) {
+		h.defaulter = d
+	}
+}
+
+// NewResourceHandler returns a new ResourceHandler for the given CustomResource.
+func NewResourceHandler(resource *CustomResource, funcs ResourceHandlerFuncs, opts ...ResourceHandlerOption) *ResourceHandler {
+	h := &ResourceHandler{resource: resource, funcs: funcs}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// OnAdd calls the AddFunc with a copy of the added object.
+func (h *ResourceHandler) OnAdd(obj interface{}) {
+	if h.funcs.AddFunc == nil {
+		return
+	}
+
+	o, err := h.convert(obj)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	h.funcs.AddFunc(o)
+}
+
+// OnUpdate calls the UpdateFunc with a copy of both objects. Periodic resyncs
+// where the ResourceVersion of both objects is the same are filtered out.
+func (h *ResourceHandler) OnUpdate(oldObj, newObj interface{}) {
+	if h.funcs.UpdateFunc == nil {
+		return
+	}
+
+	oo, err := h.convert(oldObj)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	no, err := h.convert(newObj)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	same, err := sameResourceVersion(oo, no)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	if same {
+		return
+	}
+
+	h.funcs.UpdateFunc(oo, no)
+}
+
+// OnDelete calls the DeleteFunc with a copy of the deleted object. When the
+// final state of the object is unknown, the last known state is used.
+func (h *ResourceHandler) OnDelete(obj interface{}) {
+	if h.funcs.DeleteFunc == nil {
+		return
+	}
+
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	o, err := h.convert(obj)
+	if err != nil {
+		h.handleError(err)
+		return
+	}
+
+	h.funcs.DeleteFunc(o)
+}
+
+func (h *ResourceHandler) convert(obj interface{}) (runtime.Object, error) {
+	expected := reflect.TypeOf(h.resource.Object)
+	if actual := reflect.TypeOf(obj); actual != expected {
+		return nil, &kerrors.UnexpectedTypeError{
+			Expected: fmt.Sprintf("%v", expected),
+			Actual:   fmt.Sprintf("%v", actual),
+		}
+	}
+
+	o := obj.(runtime.Object).DeepCopyObject()
+	if h.defaulter != nil {
+		if err := h.defaulter.Default(h.resource.GroupVersionKind(), o); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+func (h *ResourceHandler) handleError(err error) {
+	if h.funcs.ErrorFunc != nil {
+		h.funcs.ErrorFunc(err)
+		return
+	}
+
+	Logger.Infof("Error handling %s event: %s", h.resource.Kind(), err)
+}
+
+func sameResourceVersion(oldObj, newObj runtime.Object) (bool, error) {
+	oa, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false, err
+	}
+
+	na, err := meta.Accessor(newObj)
+	if err != nil {
+		return false, err
+	}
+
+	return oa.GetResourceVersion() == na.GetResourceVersion(), nil
+}
@@ -0,0 +1,78 @@
+package kubekit_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestResourceHandler(t *testing.T) {
+	cr := &kubekit.CustomResource{Object: &Widget{}}
+	widget := &Widget{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "1"}}
+
+	t.Run("add", func(t *testing.T) {
+		var received runtime.Object
+		h := kubekit.NewResourceHandler(cr, kubekit.ResourceHandlerFuncs{
+			AddFunc: func(obj runtime.Object) { received = obj },
+		})
+
+		h.OnAdd(widget)
+		w, ok := received.(*Widget)
+		if !ok {
+			t.Fatalf("Expected to receive a *Widget, got %T", received)
+		}
+
+		if w == widget {
+			t.Errorf("Expected to receive a copy of the object")
+		}
+	})
+
+	t.Run("update with the same resource version", func(t *testing.T) {
+		var called bool
+		h := kubekit.NewResourceHandler(cr, kubekit.ResourceHandlerFuncs{
+			UpdateFunc: func(_, _ runtime.Object) { called = true },
+		})
+
+		h.OnUpdate(widget, widget.DeepCopyObject())
+		if called {
+			t.Errorf("Expected resync events to be filtered out")
+		}
+
+		updated := widget.DeepCopyObject().(*Widget)
+		updated.ResourceVersion = "2"
+		h.OnUpdate(widget, updated)
+		if !called {
+			t.Errorf("Expected UpdateFunc to be called")
+		}
+	})
+
+	t.Run("delete with tombstone", func(t *testing.T) {
+		var received runtime.Object
+		h := kubekit.NewResourceHandler(cr, kubekit.ResourceHandlerFuncs{
+			DeleteFunc: func(obj runtime.Object) { received = obj },
+		})
+
+		h.OnDelete(cache.DeletedFinalStateUnknown{Key: "foo", Obj: widget})
+		if _, ok := received.(*Widget); !ok {
+			t.Errorf("Expected to receive a *Widget, got %T", received)
+		}
+	})
+
+	t.Run("unexpected type", func(t *testing.T) {
+		var err error
+		h := kubekit.NewResourceHandler(cr, kubekit.ResourceHandlerFuncs{
+			AddFunc:   func(obj runtime.Object) { t.Errorf("Did not expect AddFunc to be called") },
+			ErrorFunc: func(e error) { err = e },
+		})
+
+		h.OnAdd(&TestType{})
+		if !errors.IsUnexpectedType(err) {
+			t.Errorf("Expected an UnexpectedTypeError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package kubekit
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SharedInformerFactory hands out shared informers for CustomResources. The
+// informers are keyed by the GroupVersionKind of the CustomResource and the
+// namespace they watch, so multiple handlers watching the same resource share
+// a single watch connection and cache.
+type SharedInformerFactory struct {
+	resyncPeriod time.Duration
+
+	mu        sync.Mutex
+	informers map[informerKey]cache.SharedIndexInformer
+	started   map[informerKey]bool
+}
+
+type informerKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+// NewSharedInformerFactory returns a new SharedInformerFactory. The given
+// resync period is used for all the informers created by this factory.
+func NewSharedInformerFactory(resyncPeriod time.Duration) *SharedInformerFactory {
+	return &SharedInformerFactory{
+		resyncPeriod: resyncPeriod,
+		informers:    map[informerKey]cache.SharedIndexInformer{},
+		started:      map[informerKey]bool{},
+	}
+}
+
+// Informer returns the shared informer for the given CustomResource in the
+// given namespace, creating it if it doesn't exist yet. If namespace is an
+// empty string, all namespaces will be watched.
+// The cache.Getter is only used when the informer is created, it should be a
+// client for the GroupVersion of the CustomResource.
+func (f *SharedInformerFactory) Informer(cg cache.Getter, resource *CustomResource, namespace string) cache.SharedIndexInformer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := informerKey{gvk: resource.GroupVersionKind(), namespace: namespace}
+	if informer, ok := f.informers[key]; ok {
+		return informer
+	}
+
+	source := cache.NewListWatchFromClient(
+		cg,
+		resource.GetPlural(),
+		namespace,
+		fields.Everything(),
+	)
+
+	informer := cache.NewSharedIndexInformer(
+		source,
+		resource.Object,
+		f.resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	f.informers[key] = informer
+
+	return informer
+}
+
+// AddEventHandler attaches the handler to the shared informer for the given
+// CustomResource and namespace.
+func (f *SharedInformerFactory) AddEventHandler(cg cache.Getter, resource *CustomResource, namespace string, handler cache.ResourceEventHandler) {
+	f.Informer(cg, resource, namespace).AddEventHandler(handler)
+}
+
+// Start starts all the informers which haven't been started yet. Informers
+// requested after Start has been called need another call to Start. All
+// informers are stopped when the stop channel is closed.
+func (f *SharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, informer := range f.informers {
+		if f.started[key] {
+			continue
+		}
+
+		go informer.Run(stopCh)
+		f.started[key] = true
+	}
+}
+
+// WaitForCacheSync waits until the caches of all the started informers have
+// synced. It returns false if the stop channel was closed before that.
+func (f *SharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	f.mu.Lock()
+	var synced []cache.InformerSynced
+	for key, informer := range f.informers {
+		if f.started[key] {
+			synced = append(synced, informer.HasSynced)
+		}
+	}
+	f.mu.Unlock()
+
+	return cache.WaitForCacheSync(stopCh, synced...)
+}
@@ -0,0 +1,52 @@
+package kubekit_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSharedInformerFactory(t *testing.T) {
+	srv, rc := newFakeAPIServer(t, map[string][]Widget{
+		"a": {{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo", ResourceVersion: "1"}}},
+	})
+	defer srv.Close()
+
+	f := kubekit.NewSharedInformerFactory(0)
+	if f.Informer(rc, widgetResource, "a") != f.Informer(rc, widgetResource, "a") {
+		t.Errorf("Expected the same informer for the same resource and namespace")
+	}
+
+	if f.Informer(rc, widgetResource, "a") == f.Informer(rc, widgetResource, "b") {
+		t.Errorf("Expected a different informer for a different namespace")
+	}
+
+	first, second := newAddRecorder(), newAddRecorder()
+	f.AddEventHandler(rc, widgetResource, "a", first)
+	f.AddEventHandler(rc, widgetResource, "a", second)
+
+	done := make(chan struct{})
+	defer close(done)
+	f.Start(done)
+
+	if !f.WaitForCacheSync(done) {
+		t.Fatalf("Expected caches to sync")
+	}
+
+	first.expect(t, "a/foo")
+	second.expect(t, "a/foo")
+
+	lists := 0
+	paths, queries := srv.recorded()
+	for i, p := range paths {
+		if p == "/apis/kubekit/v1test1/namespaces/a/widgets" && queries[i].Get("watch") == "" {
+			lists++
+		}
+	}
+
+	if lists != 1 {
+		t.Errorf("Expected namespace a to be listed once, got %d", lists)
+	}
+}
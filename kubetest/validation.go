@@ -22,5 +22,7 @@ func GetValidator(crd kubekit.CustomResource) (*validate.SchemaValidator, error)
 	if err != nil {
 		return nil, err
 	}
-	return validation.NewSchemaValidator(val)
+
+	validator, _, err := validation.NewSchemaValidator(val)
+	return validator, err
 }
@@ -0,0 +1,203 @@
+package kubekit
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LockType represents the type of resource which is used as a lock for leader
+// election.
+type LockType string
+
+const (
+	// ConfigMapLock uses a ConfigMap to store the leader election record.
+	ConfigMapLock LockType = resourcelock.ConfigMapsResourceLock
+
+	// LeaseLock uses a coordination.k8s.io Lease to store the leader election
+	// record.
+	LeaseLock LockType = resourcelock.LeasesResourceLock
+)
+
+// Runnable represents anything that can be started by the LeaderElector, like
+// a Watcher or a Controller. The done channel is closed when leadership is
+// lost.
+type Runnable interface {
+	Run(done <-chan struct{})
+}
+
+// LeaderElectionOption represents a function that can be used to configure a
+// LeaderElector.
+type LeaderElectionOption func(le *LeaderElector)
+
+// WithIdentity sets the identity which is stored in the lock when this process
+// is the leader. Defaults to the hostname combined with a random suffix.
+func WithIdentity(id string) LeaderElectionOption {
+	return func(le *LeaderElector) {
+		le.identity = id
+	}
+}
+
+// WithLockType sets the type of resource used as a lock. Defaults to
+// `LeaseLock`.
+func WithLockType(t LockType) LeaderElectionOption {
+	return func(le *LeaderElector) {
+		le.lockType = t
+	}
+}
+
+// WithLeaseDuration sets the duration non-leaders wait before trying to
+// acquire the leadership. Defaults to `15s`.
+func WithLeaseDuration(d time.Duration) LeaderElectionOption {
+	return func(le *LeaderElector) {
+		le.leaseDuration = d
+	}
+}
+
+// WithRenewDeadline sets the duration the leader keeps retrying to renew its
+// leadership before giving up. Defaults to `10s`.
+func WithRenewDeadline(d time.Duration) LeaderElectionOption {
+	return func(le *LeaderElector) {
+		le.renewDeadline = d
+	}
+}
+
+// WithRetryPeriod sets the duration between attempts to acquire or renew the
+// leadership. Defaults to `2s`.
+func WithRetryPeriod(d time.Duration) LeaderElectionOption {
+	return func(le *LeaderElector) {
+		le.retryPeriod = d
+	}
+}
+
+// LeaderElector makes sure only one replica of a controller runs its
+// registered Runnables at a time. The Runnables are started once leadership is
+// acquired and stopped when leadership is lost.
+type LeaderElector struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+
+	identity      string
+	lockType      LockType
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+
+	runnables []Runnable
+}
+
+// NewLeaderElector sets up a new LeaderElector which uses the lock with the
+// given namespace and name.
+func NewLeaderElector(cs kubernetes.Interface, namespace, name string, opts ...LeaderElectionOption) *LeaderElector {
+	le := &LeaderElector{
+		client:        cs,
+		namespace:     namespace,
+		name:          name,
+		identity:      defaultIdentity(),
+		lockType:      LeaseLock,
+		leaseDuration: 15 * time.Second,
+		renewDeadline: 10 * time.Second,
+		retryPeriod:   2 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(le)
+	}
+
+	return le
+}
+
+// Register adds Runnables which will be started once leadership is acquired.
+func (le *LeaderElector) Register(rs ...Runnable) {
+	le.runnables = append(le.runnables, rs...)
+}
+
+// Run tries to acquire leadership and blocks until the context is cancelled or
+// leadership is lost. In the latter case errors.ErrLeadershipLost is returned, the
+// process should exit as it can't be guaranteed nobody else is leading.
+// The registered Runnables are stopped before Run returns.
+func (le *LeaderElector) Run(ctx context.Context) error {
+	lock, err := resourcelock.New(
+		string(le.lockType),
+		le.namespace,
+		le.name,
+		le.client.CoreV1(),
+		le.client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: le.identity},
+	)
+	if err != nil {
+		return err
+	}
+
+	// the callback runs in its own goroutine, guard against it starting the
+	// Runnables after Run has already returned.
+	var (
+		mu      sync.Mutex
+		stopped bool
+		wg      sync.WaitGroup
+	)
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   le.leaseDuration,
+		RenewDeadline:   le.renewDeadline,
+		RetryPeriod:     le.retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            le.name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				if stopped {
+					return
+				}
+
+				Logger.Infof("%s acquired leadership for %s/%s", le.identity, le.namespace, le.name)
+				for _, r := range le.runnables {
+					wg.Add(1)
+					go func(r Runnable) {
+						defer wg.Done()
+						r.Run(ctx.Done())
+					}(r)
+				}
+			},
+			OnStoppedLeading: func() {
+				Logger.Infof("%s stopped leading %s/%s", le.identity, le.namespace, le.name)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	elector.Run(ctx)
+
+	mu.Lock()
+	stopped = true
+	mu.Unlock()
+	wg.Wait()
+
+	if ctx.Err() == nil {
+		return kerrors.ErrLeadershipLost
+	}
+
+	return nil
+}
+
+func defaultIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return string(uuid.NewUUID())
+	}
+
+	return hostname + "_" + string(uuid.NewUUID())
+}
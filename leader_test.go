@@ -0,0 +1,88 @@
+package kubekit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type runnable struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (r *runnable) Run(done <-chan struct{}) {
+	close(r.started)
+	<-done
+	close(r.stopped)
+}
+
+func TestLeaderElector(t *testing.T) {
+	for _, lock := range []kubekit.LockType{kubekit.LeaseLock, kubekit.ConfigMapLock} {
+		t.Run(string(lock), func(t *testing.T) {
+			// the lock is created when acquiring leadership and updated when
+			// renewing it.
+			renewed := make(chan struct{}, 1)
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("update", "*", func(k8stesting.Action) (bool, runtime.Object, error) {
+				select {
+				case renewed <- struct{}{}:
+				default:
+				}
+				return false, nil, nil
+			})
+
+			r := &runnable{started: make(chan struct{}), stopped: make(chan struct{})}
+			le := kubekit.NewLeaderElector(client, "default", "test",
+				kubekit.WithIdentity("test"),
+				kubekit.WithLockType(lock),
+				kubekit.WithLeaseDuration(15*time.Second),
+				kubekit.WithRenewDeadline(10*time.Second),
+				kubekit.WithRetryPeriod(2*time.Second),
+			)
+			le.Register(r)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error)
+			go func() { errs <- le.Run(ctx) }()
+
+			select {
+			case <-r.started:
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for leadership")
+			}
+
+			// cancelling while a renewal is in flight races within client-go,
+			// wait for the first renewal to finish. The next one only happens
+			// after the retry period.
+			select {
+			case <-renewed:
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for the lease to be renewed")
+			}
+			time.Sleep(100 * time.Millisecond)
+
+			cancel()
+			select {
+			case err := <-errs:
+				if err != nil {
+					t.Errorf("Expected no error, got %s", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for Run to return")
+			}
+
+			select {
+			case <-r.stopped:
+			default:
+				t.Errorf("Expected Runnable to be stopped")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package kubekit
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Lister lists and gets objects of a CustomResource from a cache. The returned
+// objects are shared with the cache, they should be copied before they get
+// modified.
+type Lister struct {
+	indexer  cache.Indexer
+	resource *CustomResource
+}
+
+// NewLister returns a Lister which reads the objects of the CustomResource from
+// the given indexer. A Watcher provides its Lister through Watcher.Lister.
+func NewLister(indexer cache.Indexer, resource *CustomResource) *Lister {
+	return &Lister{indexer: indexer, resource: resource}
+}
+
+// List returns all the objects in the given namespace matching the selector.
+// If namespace is an empty string, the objects of all namespaces are returned.
+func (l *Lister) List(namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	var objs []runtime.Object
+	err := cache.ListAllByNamespace(l.indexer, namespace, selector, func(obj interface{}) {
+		objs = append(objs, obj.(runtime.Object))
+	})
+
+	return objs, err
+}
+
+// Get returns the object with the given namespace and name. A NotFound error
+// is returned when the object does not exist in the cache.
+func (l *Lister) Get(namespace, name string) (runtime.Object, error) {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+
+	obj, exists, err := l.indexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.NewNotFound(l.groupResource(), name)
+	}
+
+	return obj.(runtime.Object), nil
+}
+
+func (l *Lister) groupResource() schema.GroupResource {
+	return schema.GroupResource{Group: l.resource.Group, Resource: l.resource.GetPlural()}
+}
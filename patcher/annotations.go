@@ -5,13 +5,15 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/kubernetes/pkg/kubectl/resource"
+	"k8s.io/cli-runtime/pkg/resource"
 )
 
+var metadataAccessor = meta.NewAccessor()
+
 // GetOriginalConfiguration retrieves the original configuration of the object
 // from the annotation, or nil if no annotation was found.
-func GetOriginalConfiguration(name string, mapping *meta.RESTMapping, obj runtime.Object) ([]byte, error) {
-	annots, err := mapping.MetadataAccessor.Annotations(obj)
+func GetOriginalConfiguration(name string, obj runtime.Object) ([]byte, error) {
+	annots, err := metadataAccessor.Annotations(obj)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +37,7 @@ func SetOriginalConfiguration(name string, info *resource.Info, original []byte)
 		return nil
 	}
 
-	accessor := info.Mapping.MetadataAccessor
-	annots, err := accessor.Annotations(info.Object)
+	annots, err := metadataAccessor.Annotations(info.Object)
 	if err != nil {
 		return err
 	}
@@ -46,7 +47,7 @@ func SetOriginalConfiguration(name string, info *resource.Info, original []byte)
 	}
 
 	annots[namespacedAnnotation(name)] = string(original)
-	return info.Mapping.MetadataAccessor.SetAnnotations(info.Object, annots)
+	return metadataAccessor.SetAnnotations(info.Object, annots)
 }
 
 // GetModifiedConfiguration retrieves the modified configuration of the object.
@@ -58,10 +59,8 @@ func GetModifiedConfiguration(name string, info *resource.Info, annotate bool, c
 	// then add that serialization to it as the annotation and serialize it again.
 	var modified []byte
 
-	// Otherwise, use the server side version of the object.
-	accessor := info.Mapping.MetadataAccessor
 	// Get the current annotations from the object.
-	annots, err := accessor.Annotations(info.Object)
+	annots, err := metadataAccessor.Annotations(info.Object)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +71,7 @@ func GetModifiedConfiguration(name string, info *resource.Info, annotate bool, c
 
 	original := annots[namespacedAnnotation(name)]
 	delete(annots, namespacedAnnotation(name))
-	if err := accessor.SetAnnotations(info.Object, annots); err != nil {
+	if err := metadataAccessor.SetAnnotations(info.Object, annots); err != nil {
 		return nil, err
 	}
 
@@ -83,7 +82,7 @@ func GetModifiedConfiguration(name string, info *resource.Info, annotate bool, c
 
 	if annotate {
 		annots[namespacedAnnotation(name)] = string(modified)
-		if err := info.Mapping.MetadataAccessor.SetAnnotations(info.Object, annots); err != nil {
+		if err := metadataAccessor.SetAnnotations(info.Object, annots); err != nil {
 			return nil, err
 		}
 
@@ -95,7 +94,7 @@ func GetModifiedConfiguration(name string, info *resource.Info, annotate bool, c
 
 	// Restore the object to its original condition.
 	annots[namespacedAnnotation(name)] = original
-	if err := info.Mapping.MetadataAccessor.SetAnnotations(info.Object, annots); err != nil {
+	if err := metadataAccessor.SetAnnotations(info.Object, annots); err != nil {
 		return nil, err
 	}
 
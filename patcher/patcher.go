@@ -15,6 +15,8 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -22,26 +24,24 @@ import (
 	"k8s.io/apimachinery/pkg/util/mergepatch"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset"
-	"k8s.io/kubernetes/pkg/kubectl"
-	"k8s.io/kubernetes/pkg/kubectl/cmd/util/openapi"
-	"k8s.io/kubernetes/pkg/kubectl/resource"
-	"k8s.io/kubernetes/pkg/kubectl/validation"
+	"k8s.io/kubectl/pkg/util/openapi"
+	"k8s.io/kubectl/pkg/validation"
 )
 
-var backoffPeriod = time.Second
+var (
+	backoffPeriod = time.Second
+	pollInterval  = time.Second
+)
 
 // Factory represents a slimmed down version of the kubectl cmdutil
-// Factory. It is recommended to use this factory to inject into the patcher,
-// but you can provide your own implementation as well.
+// Factory, found in `k8s.io/kubectl/pkg/cmd/util`. It is recommended to use
+// this factory to inject into the patcher, but you can provide your own
+// implementation as well.
 type Factory interface {
-	Object() (meta.RESTMapper, runtime.ObjectTyper)
 	Validator(validate bool) (validation.Schema, error)
 	NewBuilder() *resource.Builder
-	ClientSet() (internalclientset.Interface, error)
-	JSONEncoder() runtime.Encoder
-	Decoder(bool) runtime.Decoder
 	OpenAPISchema() (openapi.Resources, error)
 }
 
@@ -60,6 +60,13 @@ func New(name string, f Factory, opts ...OptionFunc) *Patcher {
 	return &Patcher{Factory: f, cfg: NewConfig(opts...)}
 }
 
+// ClientOption returns a kubekit.ClientOption which sets a user agent derived
+// from the name of the Patcher. Use it for the clients of the controller, so
+// its requests can be linked to the annotations the Patcher applies.
+func (p *Patcher) ClientOption() kubekit.ClientOption {
+	return kubekit.WithUserAgent(p.cfg.name)
+}
+
 // Apply will take an object and calculate a patch for it to apply to the
 // server. When an object hasn't been created before, the object will be created
 // unless otherwise specified.
@@ -82,7 +89,7 @@ func (p *Patcher) Apply(obj runtime.Object, opts ...OptionFunc) ([]byte, error)
 		return nil, err
 	}
 
-	encoder := p.JSONEncoder()
+	encoder := unstructured.UnstructuredJSONScheme
 	var patch []byte
 	err = r.Visit(func(info *resource.Info, err error) error {
 		// Get the modified configuration of the object.
@@ -112,7 +119,7 @@ func (p *Patcher) Apply(obj runtime.Object, opts ...OptionFunc) ([]byte, error)
 					return err
 				}
 
-				if _, err := info.Mapping.UID(info.Object); err != nil {
+				if _, err := metadataAccessor.UID(info.Object); err != nil {
 					kubekit.Logger.Infof("Error getting a UID for %s: %s", info.Name, err)
 					return err
 				}
@@ -131,8 +138,7 @@ func (p *Patcher) Apply(obj runtime.Object, opts ...OptionFunc) ([]byte, error)
 				mapping:       info.Mapping,
 				helper:        newHelper(info),
 				encoder:       encoder,
-				decoder:       p.Decoder(false),
-				clientsetFunc: p.Factory.ClientSet,
+				decoder:       unstructured.UnstructuredJSONScheme,
 				openapiSchema: os,
 			}
 
@@ -162,12 +168,11 @@ func (p *Patcher) Delete(obj runtime.Object, opts ...OptionFunc) error {
 
 	return r.Visit(func(info *resource.Info, err error) error {
 		op := &objectPatcher{
-			cfg:           cfg,
-			namespace:     info.Namespace,
-			name:          info.Name,
-			mapping:       info.Mapping,
-			helper:        newHelper(info),
-			clientsetFunc: p.Factory.ClientSet,
+			cfg:       cfg,
+			namespace: info.Namespace,
+			name:      info.Name,
+			mapping:   info.Mapping,
+			helper:    newHelper(info),
 		}
 
 		return op.delete()
@@ -262,14 +267,13 @@ type objectPatcher struct {
 
 	mapping       *meta.RESTMapping
 	helper        *resource.Helper
-	clientsetFunc func() (internalclientset.Interface, error)
 	openapiSchema openapi.Resources
 }
 
 func (p *objectPatcher) patchSimple(obj runtime.Object, modified []byte) ([]byte, error) {
 	// Load the original configuration from the annotation that we've set up
 	// in the object that is currently on the server.
-	original, err := GetOriginalConfiguration(p.cfg.name, p.mapping, obj)
+	original, err := GetOriginalConfiguration(p.cfg.name, obj)
 	if err != nil {
 		kubekit.Logger.Infof("Error getting the original configuration for %s: %s", p.name, err)
 		return nil, err
@@ -328,7 +332,7 @@ func (p *objectPatcher) patchSimple(obj runtime.Object, modified []byte) ([]byte
 		return patch, nil
 	}
 
-	_, err = p.helper.Patch(p.namespace, p.name, patchType, patch)
+	_, err = p.helper.Patch(p.namespace, p.name, patchType, patch, nil)
 	return patch, err
 }
 
@@ -365,7 +369,7 @@ func (p *objectPatcher) deleteAndCreate(original runtime.Object, modified []byte
 		return modified, err
 	}
 
-	err := wait.PollImmediate(kubectl.Interval, 0, func() (bool, error) {
+	err := wait.PollImmediate(pollInterval, 0, func() (bool, error) {
 		if _, err := p.helper.Get(p.namespace, p.name, false); !errors.IsNotFound(err) {
 			return false, err
 		}
@@ -385,39 +389,28 @@ func (p *objectPatcher) create(modified []byte) ([]byte, error) {
 		return modified, err
 	}
 
-	_, err = p.helper.Create(p.namespace, true, versionedObject)
+	_, err = p.helper.Create(p.namespace, true, versionedObject, nil)
 	return modified, err
 }
 
+// delete deletes the object in the foreground, the object is only removed from
+// the server once all its dependents, like the Pods of a Deployment, are gone.
 func (p *objectPatcher) delete() error {
-	cs, err := p.clientsetFunc()
-	if err != nil {
-		return err
-	}
-
-	// look for an available reaper to gracefully shut down the underlying
-	// objects. If none exists, force delete.
-	r, err := kubectl.ReaperFor(p.mapping.GroupVersionKind.GroupKind(), cs)
-	if err != nil {
-		if _, ok := err.(*kubectl.NoSuchReaperError); !ok {
-			return err
-		}
-
-		return p.helper.Delete(p.namespace, p.name)
-	}
-
-	return r.Stop(p.namespace, p.name, 2*time.Minute, nil)
+	policy := metav1.DeletePropagationForeground
+	_, err := p.helper.DeleteWithOptions(p.namespace, p.name, &metav1.DeleteOptions{
+		PropagationPolicy: &policy,
+	})
+	return err
 }
 
 // createAndRefresh creates an object from input info and refreshes info with that object
 func createAndRefresh(info *resource.Info) error {
-	obj, err := newHelper(info).Create(info.Namespace, true, info.Object)
+	obj, err := newHelper(info).Create(info.Namespace, true, info.Object, nil)
 	if err != nil {
 		log.Printf("Error using helper")
 		return err
 	}
-	info.Refresh(obj, true)
-	return nil
+	return info.Refresh(obj, true)
 }
 
 func newHelper(info *resource.Info) *resource.Helper {
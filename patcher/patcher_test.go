@@ -1,10 +1,14 @@
 package patcher_test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/jelmersnoeck/kubekit"
 	"github.com/jelmersnoeck/kubekit/errors"
 	"github.com/jelmersnoeck/kubekit/patcher"
+
+	"k8s.io/client-go/rest"
 )
 
 func TestPatcher_Apply(t *testing.T) {
@@ -17,6 +21,15 @@ func TestPatcher_Apply(t *testing.T) {
 	})
 }
 
+func TestPatcher_ClientOption(t *testing.T) {
+	p := patcher.New("test", nil)
+	cfg := kubekit.ConfigWithOptions(&rest.Config{}, p.ClientOption())
+
+	if !strings.HasPrefix(cfg.UserAgent, "kubekit-test ") {
+		t.Errorf("Expected a user agent with the Patcher name, got '%s'", cfg.UserAgent)
+	}
+}
+
 func TestIsEmptyPatch(t *testing.T) {
 	data := []struct {
 		data []byte
@@ -8,8 +8,8 @@ import (
 	"github.com/golang/glog"
 	"github.com/jelmersnoeck/kubekit"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/kubernetes/pkg/kubectl/resource"
-	"k8s.io/kubernetes/pkg/kubectl/validation"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/kubectl/pkg/validation"
 )
 
 var defaultNamespace = "default"
@@ -1,9 +1,13 @@
 package kubekit
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"sync"
 	"time"
 
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -12,44 +16,308 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-// CreateCRD creates and registers a CRD with the k8s cluster.
-func CreateCRD(cs clientset.Interface, c CustomResource) error {
-	crd := c.Definition()
+// ConflictPolicy determines what CreateCRD does when the CRD in the cluster
+// has versions or fields which are unknown to the desired definition. This
+// usually means the CRD was installed by a newer version of the controller.
+type ConflictPolicy string
+
+const (
+	// RefuseOnConflict refuses to update the CRD and returns a
+	// errors.CRDConflictError.
+	RefuseOnConflict ConflictPolicy = "Refuse"
+
+	// WarnOnConflict logs the conflicting fields and updates the CRD anyway,
+	// removing them from the cluster.
+	WarnOnConflict ConflictPolicy = "Warn"
+)
+
+// CRDOption represents a function that can be used to configure how a CRD is
+// created or deleted.
+type CRDOption func(o *crdOptions)
 
-	if err := createCRD(cs, crd); err != nil {
+type crdOptions struct {
+	conflictPolicy ConflictPolicy
+	reporter       func(name string, changes []CRDChange)
+	timeout        time.Duration
+	rollback       bool
+	protect        bool
+	version        string
+}
+
+// WithConflictPolicy sets the ConflictPolicy. Defaults to `RefuseOnConflict`.
+func WithConflictPolicy(p ConflictPolicy) CRDOption {
+	return func(o *crdOptions) {
+		o.conflictPolicy = p
+	}
+}
+
+// WithChangeReporter sets a function which receives the changes which are
+// applied to an existing CRD. It's not called when the CRD is created or
+// already up to date. By default the changes are logged through the Logger.
+func WithChangeReporter(fn func(name string, changes []CRDChange)) CRDOption {
+	return func(o *crdOptions) {
+		o.reporter = fn
+	}
+}
+
+// WithWaitTimeout sets how long CreateCRD waits for the CRD to be established
+// and how long DeleteCRD waits for the CRD to be removed. Defaults to `60s`.
+func WithWaitTimeout(d time.Duration) CRDOption {
+	return func(o *crdOptions) {
+		o.timeout = d
+	}
+}
+
+// WithRollback undoes the changes to the CRD when it doesn't get established.
+// A created CRD is deleted again, which is safe as there can't be any objects
+// yet. An updated CRD is restored to its previous definition.
+func WithRollback() CRDOption {
+	return func(o *crdOptions) {
+		o.rollback = true
+	}
+}
+
+// WithInstanceProtection makes DeleteCRD refuse to delete a CRD while objects
+// of the CustomResource still exist. Deleting a CRD deletes all its objects.
+func WithInstanceProtection() CRDOption {
+	return func(o *crdOptions) {
+		o.protect = true
+	}
+}
+
+// WithCRDVersion sets the version of the apiextensions.k8s.io API which is used
+// to manage the CRDs, either `CRDVersionV1` or `CRDVersionV1beta1`. By default
+// v1 is used when the API server supports it.
+func WithCRDVersion(version string) CRDOption {
+	return func(o *crdOptions) {
+		o.version = version
+	}
+}
+
+func newCRDOptions(opts []CRDOption) *crdOptions {
+	o := &crdOptions{
+		conflictPolicy: RefuseOnConflict,
+		timeout:        60 * time.Second,
+		reporter: func(name string, changes []CRDChange) {
+			Logger.Infof("Updating CRD %s: %v", name, changes)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// CreateCRD creates and registers a CRD with the k8s cluster. When the CRD
+// already exists, it's only updated if it differs from the desired definition.
+// The apiextensions.k8s.io API version is discovered, see WithCRDVersion.
+// CreateCRD waits until the CRD is established, the context and WithWaitTimeout
+// can be used to limit how long.
+// When the CRD doesn't get established, it's left as is unless WithRollback is
+// used.
+func CreateCRD(ctx context.Context, cs clientset.Interface, c CustomResource, opts ...CRDOption) error {
+	if err := c.Validate(); err != nil {
 		return err
 	}
 
-	return waitForCRD(cs, c.FullName(), crd)
+	o := newCRDOptions(opts)
+	crds, err := newCRDClient(cs, o.version)
+	if err != nil {
+		return err
+	}
+
+	crd, err := crds.Definition(c)
+	if err != nil {
+		return err
+	}
+
+	previous, err := createCRD(crds, crd, o)
+	if err != nil {
+		return err
+	}
+
+	err = waitForCRD(ctx, crds, crd.Name, o.timeout)
+	if err != nil && o.rollback {
+		if rbErr := rollbackCRD(crds, crd.Name, previous); rbErr != nil {
+			return errors.NewAggregate([]error{err, rbErr})
+		}
+	}
+
+	return err
+}
+
+// CreateCRDs creates the CRDs for the given CustomResources in parallel and
+// waits for all of them to be established. The errors of the individual CRDs
+// are aggregated. The CustomResources are passed as a slice, so the options
+// can be passed as well.
+func CreateCRDs(ctx context.Context, cs clientset.Interface, crs []CustomResource, opts ...CRDOption) error {
+	return parallel(crs, func(c CustomResource) error {
+		return CreateCRD(ctx, cs, c, opts...)
+	})
 }
 
-func createCRD(cs clientset.Interface, crd *apiextv1beta1.CustomResourceDefinition) error {
-	_, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
-	if apierrors.IsAlreadyExists(err) {
-		currentCRD, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(crd.Name, metav1.GetOptions{})
+// DeleteCRD deletes the CRD of the given CustomResource and waits until it's
+// removed. Deleting a CRD which doesn't exist is not an error. When the CRD
+// isn't removed within WithWaitTimeout, errors.ErrCRDNotDeleted is returned.
+// Note that the API server deletes all objects of the CustomResource as well,
+// use WithInstanceProtection to refuse the deletion while objects exist.
+func DeleteCRD(ctx context.Context, cs clientset.Interface, c CustomResource, opts ...CRDOption) error {
+	o := newCRDOptions(opts)
+	name := c.FullName()
+	crds, err := newCRDClient(cs, o.version)
+	if err != nil {
+		return err
+	}
+
+	crd, err := crds.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if o.protect {
+		exists, err := hasInstances(cs, crd)
 		if err != nil {
 			return err
 		}
 
-		// TODO(jelmer): figure out a way here to determin if we need to update
-		// the object or not (do a diff between the current crd and the desired
-		// crd).
-		crd.ResourceVersion = currentCRD.ResourceVersion
-		_, err = cs.ApiextensionsV1beta1().CustomResourceDefinitions().Update(crd)
-		return err
+		if exists {
+			return &kerrors.CRDInUseError{Name: name}
+		}
+	}
+
+	// only delete the CRD we've looked at, not one that has been recreated
+	// in the meantime.
+	err = crds.Delete(name, &metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &crd.UID},
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
 	} else if err != nil {
 		return err
 	}
 
-	return nil
+	return waitForCRDDeletion(ctx, crds, name, o.timeout)
 }
 
-func waitForCRD(cs clientset.Interface, fullName string, crd *apiextv1beta1.CustomResourceDefinition) error {
-	err := wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
-		crd, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(
-			fullName,
-			metav1.GetOptions{},
-		)
+// DeleteCRDs deletes the CRDs of the given CustomResources in parallel. The
+// errors of the individual CRDs are aggregated.
+func DeleteCRDs(ctx context.Context, cs clientset.Interface, crs []CustomResource, opts ...CRDOption) error {
+	return parallel(crs, func(c CustomResource) error {
+		return DeleteCRD(ctx, cs, c, opts...)
+	})
+}
+
+func parallel(crs []CustomResource, fn func(CustomResource) error) error {
+	errs := make([]error, len(crs))
+
+	var wg sync.WaitGroup
+	for i := range crs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(crs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return errors.NewAggregate(errs)
+}
+
+// hasInstances checks wether or not any objects exist for the given CRD in any
+// namespace.
+func hasInstances(cs clientset.Interface, crd *apiextv1beta1.CustomResourceDefinition) (bool, error) {
+	version := crd.Spec.Version
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			version = v.Name
+			break
+		}
+	}
+
+	data, err := cs.ApiextensionsV1beta1().RESTClient().Get().
+		AbsPath("/apis", crd.Spec.Group, version, crd.Spec.Names.Plural).
+		Param("limit", "1").
+		Do().
+		Raw()
+	if err != nil {
+		return false, err
+	}
+
+	list := struct {
+		Items []json.RawMessage `json:"items"`
+	}{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return false, err
+	}
+
+	return len(list.Items) > 0, nil
+}
+
+// createCRD creates or updates the CRD and returns the CRD as it was before the
+// update. This is nil if the CRD was created.
+func createCRD(crds crdClient, crd *apiextv1beta1.CustomResourceDefinition, o *crdOptions) (*apiextv1beta1.CustomResourceDefinition, error) {
+	err := crds.Create(crd)
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	currentCRD, err := crds.Get(crd.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := crds.Diff(currentCRD, crd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changes) == 0 {
+		return currentCRD, nil
+	}
+
+	var removed []string
+	for _, change := range changes {
+		if change.Type == FieldRemoved {
+			removed = append(removed, change.Field)
+		}
+	}
+
+	if len(removed) > 0 {
+		conflict := &kerrors.CRDConflictError{Name: crd.Name, Fields: removed}
+		if o.conflictPolicy != WarnOnConflict {
+			return nil, conflict
+		}
+
+		Logger.Infof("%s, updating anyway", conflict)
+	}
+
+	o.reporter(crd.Name, changes)
+
+	crd.ResourceVersion = currentCRD.ResourceVersion
+	return currentCRD, crds.Update(crd)
+}
+
+// DiffCRD returns the changes between the spec of the CRD in the cluster and
+// the spec of the desired CRD. The values the API server defaults are set on
+// the desired CRD before comparing, so a CRD which is up to date has no
+// changes.
+func DiffCRD(current, desired *apiextv1beta1.CustomResourceDefinition) ([]CRDChange, error) {
+	desired = desired.DeepCopy()
+	apiextv1beta1.SetObjectDefaults_CustomResourceDefinition(desired)
+
+	return diffFields("spec", current.Spec, desired.Spec)
+}
+
+func waitForCRD(ctx context.Context, crds crdClient, name string, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(500*time.Millisecond, func() (bool, error) {
+		crd, err := crds.Get(name)
 		if err != nil {
 			return false, err
 		}
@@ -58,24 +326,72 @@ func waitForCRD(cs clientset.Interface, fullName string, crd *apiextv1beta1.Cust
 			switch cond.Type {
 			case apiextv1beta1.Established:
 				if cond.Status == apiextv1beta1.ConditionTrue {
-					return true, err
+					return true, nil
 				}
 			case apiextv1beta1.NamesAccepted:
 				if cond.Status == apiextv1beta1.ConditionFalse {
-					log.Printf("Name conflict: %v\n", cond.Reason)
+					return false, &kerrors.NamesNotAcceptedError{
+						Name:    name,
+						Reason:  cond.Reason,
+						Message: cond.Message,
+					}
 				}
 			}
 		}
+		return false, nil
+	}, waitCtx.Done())
+
+	if err == wait.ErrWaitTimeout {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return kerrors.ErrCRDNotEstablished
+	}
+
+	return err
+}
+
+func waitForCRDDeletion(ctx context.Context, crds crdClient, name string, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(500*time.Millisecond, func() (bool, error) {
+		_, err := crds.Get(name)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
 		return false, err
-	})
+	}, waitCtx.Done())
 
-	if err != nil {
-		deleteErr := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(fullName, nil)
-		if deleteErr != nil {
-			return errors.NewAggregate([]error{err, deleteErr})
+	if err == wait.ErrWaitTimeout {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-		return err
+		return kerrors.ErrCRDNotDeleted
 	}
 
 	return err
 }
+
+// rollbackCRD restores the CRD to its previous state. When there is no
+// previous state, the CRD was created and is deleted again.
+func rollbackCRD(crds crdClient, name string, previous *apiextv1beta1.CustomResourceDefinition) error {
+	if previous == nil {
+		Logger.Infof("Rolling back CRD %s by deleting it", name)
+		return crds.Delete(name, nil)
+	}
+
+	current, err := crds.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if current.ResourceVersion == previous.ResourceVersion {
+		return nil
+	}
+
+	Logger.Infof("Rolling back CRD %s to its previous definition", name)
+	previous = previous.DeepCopy()
+	previous.ResourceVersion = current.ResourceVersion
+	return crds.Update(previous)
+}
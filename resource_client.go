@@ -0,0 +1,178 @@
+package kubekit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// ResourceClient performs CRUD operations for a CustomResource. The plural,
+// scope and GroupVersion are derived from the CustomResource, the objects are
+// of the type of its Object.
+type ResourceClient struct {
+	client   rest.Interface
+	resource *CustomResource
+}
+
+// ObjectList is a page of objects returned by ResourceClient.List. When the
+// Continue field of the ListMeta is set, more objects can be fetched by
+// passing it to the next List call.
+type ObjectList struct {
+	metav1.ListMeta
+	Items []runtime.Object
+}
+
+// NewResourceClient returns a new ResourceClient which uses the given REST
+// client. The client should be configured for the GroupVersion of the
+// CustomResource, for example through RESTClient.
+func NewResourceClient(rc rest.Interface, resource *CustomResource) *ResourceClient {
+	return &ResourceClient{client: rc, resource: resource}
+}
+
+// Get fetches the object with the given name. For cluster scoped resources
+// the namespace is ignored.
+func (c *ResourceClient) Get(namespace, name string) (runtime.Object, error) {
+	result := c.newObject()
+	err := c.client.Get().
+		NamespaceIfScoped(namespace, c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		Name(name).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// List fetches the objects in the given namespace, or all namespaces when the
+// namespace is empty. The options can be used to filter the objects with label
+// and field selectors and to paginate through them with Limit and Continue.
+func (c *ResourceClient) List(namespace string, opts metav1.ListOptions) (*ObjectList, error) {
+	data, err := c.client.Get().
+		NamespaceIfScoped(namespace, c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		VersionedParams(&opts, metav1.ParameterCodec).
+		Do().
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := struct {
+		metav1.ListMeta `json:"metadata"`
+		Items           []json.RawMessage `json:"items"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	list := &ObjectList{ListMeta: raw.ListMeta}
+	for _, item := range raw.Items {
+		obj := c.newObject()
+		if err := json.Unmarshal(item, obj); err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, obj)
+	}
+
+	return list, nil
+}
+
+// Create creates the object in the namespace set in its metadata.
+func (c *ResourceClient) Create(obj runtime.Object) (runtime.Object, error) {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := c.newObject()
+	err = c.client.Post().
+		NamespaceIfScoped(acc.GetNamespace(), c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		Body(obj).
+		Do().
+		Into(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Update replaces the object. When the status subresource is enabled, changes
+// to the status are ignored by the API server, use UpdateStatus instead.
+func (c *ResourceClient) Update(obj runtime.Object) (runtime.Object, error) {
+	return c.put(obj, "")
+}
+
+// UpdateStatus replaces the status of the object through the /status
+// subresource.
+func (c *ResourceClient) UpdateStatus(obj runtime.Object) (runtime.Object, error) {
+	if !c.resource.HasStatusSubresource() {
+		return nil, kerrors.ErrNoStatusSubresource
+	}
+
+	return c.put(obj, "status")
+}
+
+func (c *ResourceClient) put(obj runtime.Object, subresource string) (runtime.Object, error) {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.client.Put().
+		NamespaceIfScoped(acc.GetNamespace(), c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		Name(acc.GetName())
+	if subresource != "" {
+		req = req.SubResource(subresource)
+	}
+
+	result := c.newObject()
+	if err := req.Body(obj).Do().Into(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Delete deletes the object with the given name.
+func (c *ResourceClient) Delete(namespace, name string, opts *metav1.DeleteOptions) error {
+	return c.client.Delete().
+		NamespaceIfScoped(namespace, c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		Name(name).
+		Body(opts).
+		Do().
+		Error()
+}
+
+// Watch watches the objects in the given namespace, or all namespaces when the
+// namespace is empty.
+func (c *ResourceClient) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return c.client.Get().
+		NamespaceIfScoped(namespace, c.resource.Namespaced()).
+		Resource(c.resource.GetPlural()).
+		VersionedParams(&opts, metav1.ParameterCodec).
+		Watch()
+}
+
+func (c *ResourceClient) newObject() runtime.Object {
+	return newObject(c.resource.Object)
+}
+
+// newObject returns a new, empty object of the same type as the given object.
+func newObject(obj runtime.Object) runtime.Object {
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+}
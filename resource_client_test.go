@@ -0,0 +1,145 @@
+package kubekit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+)
+
+func TestResourceClient(t *testing.T) {
+	var requests []string
+	var query map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/kubekit/v1test1/namespaces/default/widgets":
+			json.NewEncoder(w).Encode(&WidgetList{
+				ListMeta: metav1.ListMeta{Continue: "next"},
+				Items:    []Widget{{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}},
+			})
+		case r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(&Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}})
+		case r.Method == http.MethodDelete:
+			json.NewEncoder(w).Encode(&metav1.Status{Status: metav1.StatusSuccess})
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	c := kubekit.NewResourceClient(rc, widgetResource)
+	widget := &Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+
+	obj, err := c.Get("default", "foo")
+	if err != nil {
+		t.Fatalf("Expected no error getting, got %s", err)
+	}
+
+	if w, ok := obj.(*Widget); !ok || w.Name != "foo" {
+		t.Errorf("Expected to get Widget foo, got %v", obj)
+	}
+
+	list, err := c.List("default", metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"shard": "a"}).String(),
+		Limit:         1,
+		Continue:      "token",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error listing, got %s", err)
+	}
+
+	if len(list.Items) != 1 || list.Continue != "next" {
+		t.Errorf("Expected 1 item and a continue token, got %d and '%s'", len(list.Items), list.Continue)
+	}
+
+	if query["labelSelector"][0] != "shard=a" || query["limit"][0] != "1" || query["continue"][0] != "token" {
+		t.Errorf("Expected the list options to be sent, got %v", query)
+	}
+
+	if _, err := c.Create(widget); err != nil {
+		t.Errorf("Expected no error creating, got %s", err)
+	}
+
+	if _, err := c.Update(widget); err != nil {
+		t.Errorf("Expected no error updating, got %s", err)
+	}
+
+	if _, err := c.UpdateStatus(widget); !errors.IsNoStatusSubresource(err) {
+		t.Errorf("Expected ErrNoStatusSubresource, got %v", err)
+	}
+
+	if err := c.Delete("default", "foo", nil); err != nil {
+		t.Errorf("Expected no error deleting, got %s", err)
+	}
+
+	exp := []string{
+		"GET /apis/kubekit/v1test1/namespaces/default/widgets/foo",
+		"GET /apis/kubekit/v1test1/namespaces/default/widgets",
+		"POST /apis/kubekit/v1test1/namespaces/default/widgets",
+		"PUT /apis/kubekit/v1test1/namespaces/default/widgets/foo",
+		"DELETE /apis/kubekit/v1test1/namespaces/default/widgets/foo",
+	}
+
+	if len(requests) != len(exp) {
+		t.Fatalf("Expected requests %v, got %v", exp, requests)
+	}
+
+	for i := range exp {
+		if requests[i] != exp[i] {
+			t.Errorf("Expected request '%s', got '%s'", exp[i], requests[i])
+		}
+	}
+}
+
+func TestResourceClient_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := apierrors.NewInternalError(fmt.Errorf("broken"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err.ErrStatus)
+	}))
+	defer srv.Close()
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	c := kubekit.NewResourceClient(rc, widgetResource)
+	widget := &Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+
+	obj, err := c.Get("default", "foo")
+	if !apierrors.IsInternalError(err) || obj != nil {
+		t.Errorf("Expected an error without an object getting, got %v and %v", err, obj)
+	}
+
+	obj, err = c.Create(widget)
+	if !apierrors.IsInternalError(err) || obj != nil {
+		t.Errorf("Expected an error without an object creating, got %v and %v", err, obj)
+	}
+
+	obj, err = c.Update(widget)
+	if !apierrors.IsInternalError(err) || obj != nil {
+		t.Errorf("Expected an error without an object updating, got %v and %v", err, obj)
+	}
+}
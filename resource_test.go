@@ -0,0 +1,363 @@
+package kubekit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var poolResource = kubekit.CustomResource{
+	Group:   "kubekit",
+	Version: "v1",
+	Object:  &Widget{},
+	Plural:  "pools",
+}
+
+// establish defaults every created or updated CRD and marks it as
+// established, like the API server would.
+func establish(cs *fake.Clientset) {
+	fn := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		crd := action.(k8stesting.CreateAction).GetObject().(*v1beta1.CustomResourceDefinition)
+		v1beta1.SetObjectDefaults_CustomResourceDefinition(crd)
+		crd.Status.Conditions = []v1beta1.CustomResourceDefinitionCondition{
+			{Type: v1beta1.Established, Status: v1beta1.ConditionTrue},
+		}
+		return false, nil, nil
+	}
+
+	cs.PrependReactor("create", "customresourcedefinitions", fn)
+	cs.PrependReactor("update", "customresourcedefinitions", fn)
+}
+
+func updates(cs *fake.Clientset) int {
+	var n int
+	for _, action := range cs.Actions() {
+		if action.GetVerb() == "update" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCreateCRD(t *testing.T) {
+	t.Run("up to date", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		establish(cs)
+
+		if err := kubekit.CreateCRD(context.Background(), cs, poolResource); err != nil {
+			t.Fatalf("Expected no error creating the CRD, got %s", err)
+		}
+
+		var reported bool
+		err := kubekit.CreateCRD(context.Background(), cs, poolResource, kubekit.WithChangeReporter(func(string, []kubekit.CRDChange) {
+			reported = true
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if n := updates(cs); n != 0 || reported {
+			t.Errorf("Expected no updates for an unchanged CRD, got %d", n)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		establish(cs)
+
+		if err := kubekit.CreateCRD(context.Background(), cs, poolResource); err != nil {
+			t.Fatalf("Expected no error creating the CRD, got %s", err)
+		}
+
+		cr := poolResource
+		cr.Aliases = []string{"pl"}
+
+		var changes []kubekit.CRDChange
+		err := kubekit.CreateCRD(context.Background(), cs, cr, kubekit.WithChangeReporter(func(_ string, c []kubekit.CRDChange) {
+			changes = c
+		}))
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		exp := kubekit.CRDChange{Field: "spec.names.shortNames", Type: kubekit.FieldAdded}
+		if len(changes) != 1 || changes[0] != exp {
+			t.Errorf("Expected changes [%s], got %v", exp, changes)
+		}
+
+		if n := updates(cs); n != 1 {
+			t.Errorf("Expected 1 update, got %d", n)
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		newer := poolResource
+		newer.Versions = []kubekit.CustomResourceVersion{
+			{Name: "v1", Served: true, Object: &Widget{}},
+			{Name: "v2", Served: true, Storage: true, Object: &Widget{}},
+		}
+
+		crd := newer.Definition()
+		v1beta1.SetObjectDefaults_CustomResourceDefinition(crd)
+
+		cs := fake.NewSimpleClientset(crd)
+		establish(cs)
+
+		err := kubekit.CreateCRD(context.Background(), cs, poolResource)
+		if !errors.IsCRDConflict(err) {
+			t.Fatalf("Expected a CRDConflictError, got %v", err)
+		}
+
+		if n := updates(cs); n != 0 {
+			t.Errorf("Expected the CRD not to be updated, got %d updates", n)
+		}
+
+		err = kubekit.CreateCRD(context.Background(), cs, poolResource, kubekit.WithConflictPolicy(kubekit.WarnOnConflict))
+		if err != nil {
+			t.Fatalf("Expected no error with WarnOnConflict, got %s", err)
+		}
+
+		if n := updates(cs); n != 1 {
+			t.Errorf("Expected the CRD to be updated, got %d updates", n)
+		}
+	})
+}
+
+func TestCreateCRD_V1(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	cs.Resources = []*metav1.APIResourceList{{GroupVersion: "apiextensions.k8s.io/v1"}}
+	cs.PrependReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		crd, ok := action.(k8stesting.CreateAction).GetObject().(*apiextv1.CustomResourceDefinition)
+		if !ok {
+			t.Fatalf("Expected a v1 CRD to be created, got %T", action.(k8stesting.CreateAction).GetObject())
+		}
+
+		apiextv1.SetObjectDefaults_CustomResourceDefinition(crd)
+		crd.Status.Conditions = []apiextv1.CustomResourceDefinitionCondition{
+			{Type: apiextv1.Established, Status: apiextv1.ConditionTrue},
+		}
+		return false, nil, nil
+	})
+
+	if err := kubekit.CreateCRD(context.Background(), cs, poolResource); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	crd, err := cs.ApiextensionsV1().CustomResourceDefinitions().Get(poolResource.FullName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected the v1 CRD to exist, got %s", err)
+	}
+
+	if len(crd.Spec.Versions) != 1 || crd.Spec.Versions[0].Schema == nil {
+		t.Errorf("Expected a version with a schema, got %v", crd.Spec.Versions)
+	}
+
+	if err := kubekit.CreateCRD(context.Background(), cs, poolResource); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if n := updates(cs); n != 0 {
+		t.Errorf("Expected no updates for an unchanged CRD, got %d", n)
+	}
+}
+
+func TestDiffCRD(t *testing.T) {
+	current := poolResource.Definition()
+	v1beta1.SetObjectDefaults_CustomResourceDefinition(current)
+
+	cr := poolResource
+	cr.Versions = []kubekit.CustomResourceVersion{
+		{Name: "v1", Served: false, Object: &Widget{}},
+		{Name: "v2", Served: true, Storage: true, Object: &Widget{}},
+	}
+
+	changes, err := kubekit.DiffCRD(current, cr.Definition())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	exp := []kubekit.CRDChange{
+		{Field: "spec.versions[v1].served", Type: kubekit.FieldModified},
+		{Field: "spec.versions[v1].storage", Type: kubekit.FieldModified},
+		{Field: "spec.versions[v2]", Type: kubekit.FieldAdded},
+	}
+
+	if len(changes) != len(exp) {
+		t.Fatalf("Expected changes %v, got %v", exp, changes)
+	}
+
+	for i := range exp {
+		if changes[i] != exp[i] {
+			t.Errorf("Expected change %s, got %s", exp[i], changes[i])
+		}
+	}
+}
+
+func TestCreateCRD_Wait(t *testing.T) {
+	t.Run("names not accepted", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+		cs.PrependReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			crd := action.(k8stesting.CreateAction).GetObject().(*v1beta1.CustomResourceDefinition)
+			crd.Status.Conditions = []v1beta1.CustomResourceDefinitionCondition{
+				{Type: v1beta1.NamesAccepted, Status: v1beta1.ConditionFalse, Reason: "ListKindConflict"},
+			}
+			return false, nil, nil
+		})
+
+		err := kubekit.CreateCRD(context.Background(), cs, poolResource)
+		nerr, ok := err.(*errors.NamesNotAcceptedError)
+		if !ok {
+			t.Fatalf("Expected a NamesNotAcceptedError, got %v", err)
+		}
+
+		if nerr.Reason != "ListKindConflict" {
+			t.Errorf("Expected reason 'ListKindConflict', got '%s'", nerr.Reason)
+		}
+
+		if _, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(poolResource.FullName(), metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected the CRD not to be deleted, got %s", err)
+		}
+	})
+
+	t.Run("timeout with rollback", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+
+		err := kubekit.CreateCRD(context.Background(), cs, poolResource,
+			kubekit.WithWaitTimeout(10*time.Millisecond),
+			kubekit.WithRollback(),
+		)
+		if !errors.IsCRDNotEstablished(err) {
+			t.Fatalf("Expected ErrCRDNotEstablished, got %v", err)
+		}
+
+		_, err = cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(poolResource.FullName(), metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("Expected the created CRD to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cs := fake.NewSimpleClientset()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := kubekit.CreateCRD(ctx, cs, poolResource); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestCreateCRDs(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	establish(cs)
+
+	widgets := poolResource
+	widgets.Plural = "widgets"
+
+	crs := []kubekit.CustomResource{poolResource, widgets}
+	if err := kubekit.CreateCRDs(context.Background(), cs, crs); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	for _, cr := range crs {
+		if _, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(cr.FullName(), metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected CRD %s to be created, got %s", cr.FullName(), err)
+		}
+	}
+
+	invalid := kubekit.CustomResource{Group: "kubekit", Version: "v1", Plural: "invalids"}
+	err := kubekit.CreateCRDs(context.Background(), cs, []kubekit.CustomResource{poolResource, invalid})
+	agg, ok := err.(utilerrors.Aggregate)
+	if !ok || len(agg.Errors()) != 1 {
+		t.Errorf("Expected an aggregate with 1 error, got %v", err)
+	}
+
+	if err := kubekit.DeleteCRDs(context.Background(), cs, crs); err != nil {
+		t.Fatalf("Expected no error deleting, got %s", err)
+	}
+
+	for _, cr := range crs {
+		_, err := cs.ApiextensionsV1beta1().CustomResourceDefinitions().Get(cr.FullName(), metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("Expected CRD %s to be deleted, got %v", cr.FullName(), err)
+		}
+	}
+
+	if err := kubekit.DeleteCRD(context.Background(), cs, poolResource); err != nil {
+		t.Errorf("Expected no error deleting a missing CRD, got %s", err)
+	}
+}
+
+func TestDeleteCRD_Timeout(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	establish(cs)
+
+	if err := kubekit.CreateCRD(context.Background(), cs, poolResource); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	// the CRD is kept around, like it would be while a finalizer is pending.
+	cs.PrependReactor("delete", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+
+	err := kubekit.DeleteCRD(context.Background(), cs, poolResource, kubekit.WithWaitTimeout(10*time.Millisecond))
+	if !errors.IsCRDNotDeleted(err) {
+		t.Errorf("Expected ErrCRDNotDeleted, got %v", err)
+	}
+}
+
+func TestDeleteCRD_InstanceProtection(t *testing.T) {
+	crd := poolResource.Definition()
+	v1beta1.SetObjectDefaults_CustomResourceDefinition(crd)
+
+	var deleted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = true
+			json.NewEncoder(w).Encode(&metav1.Status{Status: metav1.StatusSuccess})
+		case r.URL.Path == "/apis/kubekit/v1/pools":
+			if l := r.URL.Query().Get("limit"); l != "1" {
+				t.Errorf("Expected limit '1', got '%s'", l)
+			}
+			w.Write([]byte(`{"items":[{"metadata":{"name":"foo"}}]}`))
+		default:
+			json.NewEncoder(w).Encode(crd)
+		}
+	}))
+	defer srv.Close()
+
+	cs, err := clientset.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("Could not create clientset: %s", err)
+	}
+
+	err = kubekit.DeleteCRD(context.Background(), cs, poolResource, kubekit.WithInstanceProtection())
+	if !errors.IsCRDInUse(err) {
+		t.Errorf("Expected a CRDInUseError, got %v", err)
+	}
+
+	if deleted {
+		t.Errorf("Expected the CRD not to be deleted")
+	}
+}
@@ -0,0 +1,71 @@
+package kubekit
+
+import (
+	"encoding/json"
+
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/client-go/rest"
+)
+
+// GetScale fetches the scale subresource of the given object. The
+// CustomResource needs to have its scale subresource enabled and the REST
+// client should be configured for the GroupVersion of the CustomResource.
+func GetScale(rc rest.Interface, resource *CustomResource, namespace, name string) (*autoscalingv1.Scale, error) {
+	if !resource.HasScaleSubresource() {
+		return nil, kerrors.ErrNoScaleSubresource
+	}
+
+	data, err := rc.Get().
+		NamespaceIfScoped(namespace, resource.Namespaced()).
+		Resource(resource.GetPlural()).
+		Name(name).
+		SubResource("scale").
+		Do().
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	scale := &autoscalingv1.Scale{}
+	if err := json.Unmarshal(data, scale); err != nil {
+		return nil, err
+	}
+
+	return scale, nil
+}
+
+// UpdateScale sets the desired amount of replicas of the given object through
+// its scale subresource.
+func UpdateScale(rc rest.Interface, resource *CustomResource, namespace, name string, replicas int32) (*autoscalingv1.Scale, error) {
+	scale, err := GetScale(rc, resource, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	scale.Spec.Replicas = replicas
+	body, err := json.Marshal(scale)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := rc.Put().
+		NamespaceIfScoped(namespace, resource.Namespaced()).
+		Resource(resource.GetPlural()).
+		Name(name).
+		SubResource("scale").
+		Body(body).
+		Do().
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &autoscalingv1.Scale{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
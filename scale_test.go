@@ -0,0 +1,74 @@
+package kubekit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/client-go/rest"
+)
+
+func TestScale(t *testing.T) {
+	cr := *widgetResource
+	cr.Subresources = &v1beta1.CustomResourceSubresources{
+		Scale: &v1beta1.CustomResourceSubresourceScale{
+			SpecReplicasPath:   ".spec.replicas",
+			StatusReplicasPath: ".status.replicas",
+		},
+	}
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(r.URL.Path, "/broken/") {
+			w.Write([]byte(`{"spec": {"replicas": "one"}}`))
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+			return
+		}
+
+		scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 1}}
+		json.NewEncoder(w).Encode(scale)
+	}))
+	defer srv.Close()
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	if _, err := kubekit.GetScale(rc, widgetResource, "default", "foo"); !errors.IsNoScaleSubresource(err) {
+		t.Errorf("Expected ErrNoScaleSubresource, got %v", err)
+	}
+
+	if scale, err := kubekit.GetScale(rc, &cr, "default", "broken"); err == nil || scale != nil {
+		t.Errorf("Expected a decoding error and no Scale, got %v and %v", scale, err)
+	}
+
+	scale, err := kubekit.UpdateScale(rc, &cr, "default", "foo", 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if scale.Spec.Replicas != 3 {
+		t.Errorf("Expected 3 replicas, got %d", scale.Spec.Replicas)
+	}
+
+	if exp := "PUT /apis/kubekit/v1test1/namespaces/default/widgets/foo/scale"; paths[len(paths)-1] != exp {
+		t.Errorf("Expected request '%s', got '%s'", exp, paths[len(paths)-1])
+	}
+}
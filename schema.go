@@ -0,0 +1,305 @@
+package kubekit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// SchemaTag is the struct tag which can be used to refine the generated
+// validation schema of a field. Options are separated by a semicolon:
+//
+//	Replicas int32  `json:"replicas" kubekit:"min=1;max=10"`
+//	Name     string `json:"name" kubekit:"pattern=^[a-z]+$;max=63"`
+//	Policy   string `json:"policy,omitempty" kubekit:"enum=Always|Never"`
+//	Image    string `json:"image,omitempty" kubekit:"required"`
+//
+// `min` and `max` translate to the minimum and maximum for numbers, the length
+// for strings and the amount of items for lists. `required` and `optional`
+// overwrite the requirement derived from the json tag.
+const SchemaTag = "kubekit"
+
+var (
+	timeType        = reflect.TypeOf(metav1.Time{})
+	durationType    = reflect.TypeOf(metav1.Duration{})
+	objectMetaType  = reflect.TypeOf(metav1.ObjectMeta{})
+	intOrStringType = reflect.TypeOf(intstr.IntOrString{})
+	quantityType    = reflect.TypeOf(resource.Quantity{})
+	rawType         = reflect.TypeOf(runtime.RawExtension{})
+)
+
+// GenerateValidation generates the OpenAPI validation schema for the given
+// object by reflecting over its Go type. Fields are named after their json
+// tags. Fields without `omitempty` which aren't pointers are required.
+func GenerateValidation(obj runtime.Object) (*v1beta1.CustomResourceValidation, error) {
+	g := &schemaGenerator{seen: map[reflect.Type]bool{}}
+
+	props, err := g.schema(reflect.TypeOf(obj))
+	if err != nil {
+		return nil, err
+	}
+
+	// the API server manages the metadata of an object, it can't be further
+	// specified.
+	if _, ok := props.Properties["metadata"]; ok {
+		props.Properties["metadata"] = v1beta1.JSONSchemaProps{Type: "object"}
+	}
+
+	var required []string
+	for _, r := range props.Required {
+		if r != "apiVersion" && r != "kind" && r != "metadata" {
+			required = append(required, r)
+		}
+	}
+	props.Required = required
+
+	return &v1beta1.CustomResourceValidation{OpenAPIV3Schema: props}, nil
+}
+
+type schemaGenerator struct {
+	seen map[reflect.Type]bool
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) (*v1beta1.JSONSchemaProps, error) {
+	t = derefType(t)
+
+	switch t {
+	case timeType:
+		return &v1beta1.JSONSchemaProps{Type: "string", Format: "date-time"}, nil
+	case durationType:
+		return &v1beta1.JSONSchemaProps{Type: "string"}, nil
+	case objectMetaType:
+		return &v1beta1.JSONSchemaProps{Type: "object"}, nil
+	case intOrStringType, quantityType:
+		return &v1beta1.JSONSchemaProps{XIntOrString: true}, nil
+	case rawType:
+		preserve := true
+		return &v1beta1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: &preserve}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &v1beta1.JSONSchemaProps{Type: "string"}, nil
+	case reflect.Bool:
+		return &v1beta1.JSONSchemaProps{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &v1beta1.JSONSchemaProps{Type: "integer", Format: "int64"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &v1beta1.JSONSchemaProps{Type: "integer", Format: "int32"}, nil
+	case reflect.Float32:
+		return &v1beta1.JSONSchemaProps{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &v1beta1.JSONSchemaProps{Type: "number", Format: "double"}, nil
+	case reflect.Interface:
+		preserve := true
+		return &v1beta1.JSONSchemaProps{XPreserveUnknownFields: &preserve}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &v1beta1.JSONSchemaProps{Type: "string", Format: "byte"}, nil
+		}
+
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &v1beta1.JSONSchemaProps{
+			Type:  "array",
+			Items: &v1beta1.JSONSchemaPropsOrArray{Schema: items},
+		}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map %s should have string keys", t)
+		}
+
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &v1beta1.JSONSchemaProps{
+			Type:                 "object",
+			AdditionalProperties: &v1beta1.JSONSchemaPropsOrBool{Allows: true, Schema: values},
+		}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	return nil, fmt.Errorf("can't generate a schema for type %s", t)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (*v1beta1.JSONSchemaProps, error) {
+	if g.seen[t] {
+		return nil, fmt.Errorf("can't generate a schema for recursive type %s", t)
+	}
+	g.seen[t] = true
+	defer delete(g.seen, t)
+
+	props := &v1beta1.JSONSchemaProps{
+		Type:       "object",
+		Properties: map[string]v1beta1.JSONSchemaProps{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, inline := jsonTag(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldProps, err := g.schema(field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if inline {
+			for n, p := range fieldProps.Properties {
+				props.Properties[n] = p
+			}
+			props.Required = append(props.Required, fieldProps.Required...)
+			continue
+		}
+
+		required := !omitEmpty(field) && field.Type.Kind() != reflect.Ptr
+		required, err = applySchemaTag(fieldProps, field, required)
+		if err != nil {
+			return nil, err
+		}
+
+		props.Properties[name] = *fieldProps
+		if required {
+			props.Required = append(props.Required, name)
+		}
+	}
+
+	return props, nil
+}
+
+// applySchemaTag applies the options of the SchemaTag to the given props and
+// returns wether or not the field is required.
+func applySchemaTag(props *v1beta1.JSONSchemaProps, field reflect.StructField, required bool) (bool, error) {
+	tag := field.Tag.Get(SchemaTag)
+	if tag == "" {
+		return required, nil
+	}
+
+	for _, opt := range strings.Split(tag, ";") {
+		parts := strings.SplitN(opt, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		var err error
+		switch key {
+		case "required":
+			required = true
+		case "optional":
+			required = false
+		case "pattern":
+			props.Pattern = value
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				raw, err := enumValue(props.Type, v)
+				if err != nil {
+					return false, fmt.Errorf("invalid enum value for %s: %s", field.Name, err)
+				}
+				props.Enum = append(props.Enum, v1beta1.JSON{Raw: raw})
+			}
+		case "min", "max":
+			err = applyLimit(props, key == "min", value)
+		default:
+			err = fmt.Errorf("unknown option %s", key)
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("invalid %s tag for %s: %s", SchemaTag, field.Name, err)
+		}
+	}
+
+	return required, nil
+}
+
+func applyLimit(props *v1beta1.JSONSchemaProps, min bool, value string) error {
+	switch props.Type {
+	case "integer", "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+
+		if min {
+			props.Minimum = &f
+		} else {
+			props.Maximum = &f
+		}
+	case "string", "array":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case props.Type == "string" && min:
+			props.MinLength = &i
+		case props.Type == "string":
+			props.MaxLength = &i
+		case min:
+			props.MinItems = &i
+		default:
+			props.MaxItems = &i
+		}
+	default:
+		return fmt.Errorf("limits are not supported for type %s", props.Type)
+	}
+
+	return nil
+}
+
+func enumValue(typ, value string) ([]byte, error) {
+	switch typ {
+	case "integer":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(i)
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(f)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(b)
+	}
+
+	return json.Marshal(value)
+}
+
+func omitEmpty(field reflect.StructField) bool {
+	for _, opt := range strings.Split(field.Tag.Get("json"), ",")[1:] {
+		if opt == "omitempty" {
+			return true
+		}
+	}
+
+	return false
+}
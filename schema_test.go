@@ -0,0 +1,138 @@
+package kubekit_test
+
+import (
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+type App struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AppSpec    `json:"spec"`
+	Status *AppStatus `json:"status,omitempty"`
+}
+
+func (a *App) DeepCopyObject() runtime.Object { return a }
+
+type Source struct {
+	Image string `json:"image,omitempty"`
+}
+
+type AppSpec struct {
+	Source `json:",inline"`
+
+	Name     string             `json:"name" kubekit:"pattern=^[a-z]{1,3}$;max=63"`
+	Replicas int32              `json:"replicas" kubekit:"min=1;max=10"`
+	Policy   string             `json:"policy,omitempty" kubekit:"enum=Always|Never;required"`
+	Ports    []int              `json:"ports,omitempty" kubekit:"min=1"`
+	Env      map[string]string  `json:"env,omitempty"`
+	Port     intstr.IntOrString `json:"port,omitempty"`
+	Since    metav1.Time        `json:"since,omitempty"`
+	Ignored  string             `json:"-"`
+}
+
+type AppStatus struct {
+	Ready bool `json:"ready"`
+}
+
+type Node struct {
+	metav1.TypeMeta `json:",inline"`
+	Children        []Node `json:"children"`
+}
+
+func (n *Node) DeepCopyObject() runtime.Object { return n }
+
+type BadTag struct {
+	metav1.TypeMeta `json:",inline"`
+	Name            string `json:"name" kubekit:"min=foo"`
+}
+
+func (b *BadTag) DeepCopyObject() runtime.Object { return b }
+
+func TestGenerateValidation(t *testing.T) {
+	val, err := kubekit.GenerateValidation(&App{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	root := val.OpenAPIV3Schema
+	for _, name := range []string{"apiVersion", "kind", "metadata", "spec", "status"} {
+		if _, ok := root.Properties[name]; !ok {
+			t.Errorf("Expected property '%s' to exist", name)
+		}
+	}
+
+	if len(root.Required) != 1 || root.Required[0] != "spec" {
+		t.Errorf("Expected only spec to be required, got %v", root.Required)
+	}
+
+	if props := root.Properties["metadata"]; props.Type != "object" || len(props.Properties) != 0 {
+		t.Errorf("Expected metadata to be a plain object")
+	}
+
+	spec := root.Properties["spec"]
+	if _, ok := spec.Properties["image"]; !ok {
+		t.Errorf("Expected inlined property 'image' to exist")
+	}
+
+	if _, ok := spec.Properties["Ignored"]; ok {
+		t.Errorf("Did not expect ignored field to exist")
+	}
+
+	required := map[string]bool{}
+	for _, r := range spec.Required {
+		required[r] = true
+	}
+	for _, name := range []string{"name", "replicas", "policy"} {
+		if !required[name] {
+			t.Errorf("Expected '%s' to be required", name)
+		}
+	}
+	if required["image"] || required["ports"] {
+		t.Errorf("Did not expect omitempty fields to be required, got %v", spec.Required)
+	}
+
+	name := spec.Properties["name"]
+	if name.Pattern != "^[a-z]{1,3}$" || name.MaxLength == nil || *name.MaxLength != 63 {
+		t.Errorf("Expected pattern and max length to be set for name")
+	}
+
+	replicas := spec.Properties["replicas"]
+	if replicas.Type != "integer" || *replicas.Minimum != 1 || *replicas.Maximum != 10 {
+		t.Errorf("Expected replicas to be an integer between 1 and 10")
+	}
+
+	if policy := spec.Properties["policy"]; len(policy.Enum) != 2 || string(policy.Enum[0].Raw) != `"Always"` {
+		t.Errorf("Expected policy to have an enum of 2 values")
+	}
+
+	if ports := spec.Properties["ports"]; ports.Type != "array" || ports.Items.Schema.Type != "integer" || *ports.MinItems != 1 {
+		t.Errorf("Expected ports to be an array of integers with at least one item")
+	}
+
+	if env := spec.Properties["env"]; env.AdditionalProperties == nil || env.AdditionalProperties.Schema.Type != "string" {
+		t.Errorf("Expected env to be a map of strings")
+	}
+
+	if port := spec.Properties["port"]; !port.XIntOrString {
+		t.Errorf("Expected port to be an int or string")
+	}
+
+	if since := spec.Properties["since"]; since.Type != "string" || since.Format != "date-time" {
+		t.Errorf("Expected since to be a date-time string")
+	}
+
+	if _, err := kubekit.GenerateValidation(&Node{}); err == nil {
+		t.Errorf("Expected an error for recursive types")
+	}
+
+	if _, err := kubekit.GenerateValidation(&BadTag{}); err == nil {
+		t.Errorf("Expected an error for an invalid tag")
+	}
+}
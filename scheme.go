@@ -0,0 +1,66 @@
+package kubekit
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ListProvider can be implemented by the Object of a CustomResource to provide
+// its List type, so it doesn't need to be declared as ListObject.
+type ListProvider interface {
+	NewList() runtime.Object
+}
+
+// NewSchemeBuilder returns a SchemeBuilder which registers the Objects of the
+// given CustomResources and all their versions with a scheme, together with
+// their List types and the metav1 types for their GroupVersions.
+// The List type is taken from ListObject or, when that's not set, from the
+// NewList method of the Object. It needs to be named after the kind with a
+// `List` suffix.
+func NewSchemeBuilder(crs ...CustomResource) SchemeBuilder {
+	return func(s *runtime.Scheme) error {
+		groupVersions := map[schema.GroupVersion]bool{}
+		for _, cr := range crs {
+			types := []CustomResourceVersion{{Name: cr.GroupVersion().Version, Object: cr.Object, ListObject: cr.ListObject}}
+			if cr.Object == nil {
+				types = nil
+			}
+			types = append(types, cr.Versions...)
+
+			for _, t := range types {
+				gv := schema.GroupVersion{Group: cr.Group, Version: t.Name}
+				list, err := listObject(t.Object, t.ListObject)
+				if err != nil {
+					return fmt.Errorf("can't register %s %s: %s", gv, cr.Kind(), err)
+				}
+
+				s.AddKnownTypes(gv, t.Object, list)
+				if !groupVersions[gv] {
+					metav1.AddToGroupVersion(s, gv)
+					groupVersions[gv] = true
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+func listObject(obj, list runtime.Object) (runtime.Object, error) {
+	if list == nil {
+		provider, ok := obj.(ListProvider)
+		if !ok {
+			return nil, fmt.Errorf("the List type of %T can't be found, set ListObject or implement NewList", obj)
+		}
+		list = provider.NewList()
+	}
+
+	if exp := TypeName(obj) + "List"; TypeName(list) != exp {
+		return nil, fmt.Errorf("the List type of %T should be named %s, got %T", obj, exp, list)
+	}
+
+	return list, nil
+}
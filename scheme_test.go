@@ -0,0 +1,70 @@
+package kubekit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type Gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (g *Gadget) DeepCopyObject() runtime.Object { return g }
+func (g *Gadget) NewList() runtime.Object        { return &GadgetList{} }
+
+type GadgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Gadget `json:"items"`
+}
+
+func (l *GadgetList) DeepCopyObject() runtime.Object { return l }
+
+func TestNewSchemeBuilder(t *testing.T) {
+	widgets := *widgetResource
+	widgets.ListObject = &WidgetList{}
+
+	gadgets := kubekit.CustomResource{
+		Group: "kubekit",
+		Versions: []kubekit.CustomResourceVersion{
+			{Name: "v1", Served: true, Object: &Gadget{}},
+			{Name: "v2", Served: true, Storage: true, Object: &Gadget{}},
+		},
+	}
+
+	s := runtime.NewScheme()
+	if err := kubekit.NewSchemeBuilder(widgets, gadgets)(s); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	kinds := []string{"kubekit/v1test1/Widget", "kubekit/v1test1/WidgetList", "kubekit/v1/GadgetList", "kubekit/v2/Gadget", "kubekit/v2/ListOptions"}
+	for _, kind := range kinds {
+		parts := strings.Split(kind, "/")
+		gvk := widgetGroupVersion.WithKind(parts[2])
+		gvk.Version = parts[1]
+		if !s.Recognizes(gvk) {
+			t.Errorf("Expected %s to be registered", kind)
+		}
+	}
+
+	t.Run("missing list type", func(t *testing.T) {
+		cr := kubekit.CustomResource{Group: "kubekit", Version: "v1", Object: &Pool{}}
+		err := kubekit.NewSchemeBuilder(cr)(runtime.NewScheme())
+		if err == nil || !strings.Contains(err.Error(), "List type of *kubekit_test.Pool can't be found") {
+			t.Errorf("Expected an error about the missing List type, got %v", err)
+		}
+	})
+
+	t.Run("wrongly named list type", func(t *testing.T) {
+		cr := kubekit.CustomResource{Group: "kubekit", Version: "v1", Object: &Pool{}, ListObject: &WidgetList{}}
+		if err := kubekit.NewSchemeBuilder(cr)(runtime.NewScheme()); err == nil {
+			t.Errorf("Expected an error for a List type with the wrong name")
+		}
+	})
+}
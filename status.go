@@ -0,0 +1,100 @@
+package kubekit
+
+import (
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+// StatusWriter writes the status of CustomResource objects through the /status
+// subresource, which leaves the rest of the object untouched. The
+// CustomResource needs to have its status subresource enabled.
+type StatusWriter struct {
+	client   rest.Interface
+	resource *CustomResource
+	backoff  wait.Backoff
+}
+
+// NewStatusWriter returns a new StatusWriter which uses the given REST client.
+// The client should be configured for the GroupVersion of the CustomResource,
+// for example through RESTClient.
+func NewStatusWriter(rc rest.Interface, resource *CustomResource) *StatusWriter {
+	return &StatusWriter{
+		client:   rc,
+		resource: resource,
+		backoff:  retry.DefaultRetry,
+	}
+}
+
+// UpdateStatus fetches the latest version of the object, calls mutate to
+// update its status and writes the result to the /status subresource. When the
+// object has been modified in the meantime, this is retried with a fresh copy.
+func (s *StatusWriter) UpdateStatus(namespace, name string, mutate func(obj runtime.Object) error) (runtime.Object, error) {
+	if !s.resource.HasStatusSubresource() {
+		return nil, kerrors.ErrNoStatusSubresource
+	}
+
+	var result runtime.Object
+	err := retry.RetryOnConflict(s.backoff, func() error {
+		obj := s.resource.Object.DeepCopyObject()
+		err := s.client.Get().
+			NamespaceIfScoped(namespace, s.resource.Namespaced()).
+			Resource(s.resource.GetPlural()).
+			Name(name).
+			Do().
+			Into(obj)
+		if err != nil {
+			return err
+		}
+
+		if err := mutate(obj); err != nil {
+			return err
+		}
+
+		result = s.resource.Object.DeepCopyObject()
+		return s.client.Put().
+			NamespaceIfScoped(namespace, s.resource.Namespaced()).
+			Resource(s.resource.GetPlural()).
+			Name(name).
+			SubResource("status").
+			Body(obj).
+			Do().
+			Into(result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PatchStatus sends the given patch to the /status subresource of the object.
+// Conflicts, which can occur when the patch contains a resourceVersion, are
+// retried.
+func (s *StatusWriter) PatchStatus(namespace, name string, pt types.PatchType, data []byte) (runtime.Object, error) {
+	if !s.resource.HasStatusSubresource() {
+		return nil, kerrors.ErrNoStatusSubresource
+	}
+
+	var result runtime.Object
+	err := retry.RetryOnConflict(s.backoff, func() error {
+		result = s.resource.Object.DeepCopyObject()
+		return s.client.Patch(pt).
+			NamespaceIfScoped(namespace, s.resource.Namespaced()).
+			Resource(s.resource.GetPlural()).
+			Name(name).
+			SubResource("status").
+			Body(data).
+			Do().
+			Into(result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
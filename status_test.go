@@ -0,0 +1,133 @@
+package kubekit_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func TestStatusWriter(t *testing.T) {
+	cr := *widgetResource
+	cr.Subresources = &v1beta1.CustomResourceSubresources{
+		Status: &v1beta1.CustomResourceSubresourceStatus{},
+	}
+
+	var requests []string
+	conflicts := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, "/broken/status") {
+			err := apierrors.NewInternalError(fmt.Errorf("broken"))
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(err.ErrStatus)
+			return
+		}
+
+		if r.Method != http.MethodGet && conflicts > 0 {
+			conflicts--
+			err := apierrors.NewConflict(schema.GroupResource{Group: "kubekit", Resource: "widgets"}, "foo", nil)
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(err.ErrStatus)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(&Widget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}})
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: srv.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	t.Run("without status subresource", func(t *testing.T) {
+		sw := kubekit.NewStatusWriter(rc, widgetResource)
+		if _, err := sw.PatchStatus("default", "foo", types.MergePatchType, []byte("{}")); !errors.IsNoStatusSubresource(err) {
+			t.Errorf("Expected ErrNoStatusSubresource, got %v", err)
+		}
+	})
+
+	t.Run("update status", func(t *testing.T) {
+		requests = nil
+		conflicts = 1
+
+		sw := kubekit.NewStatusWriter(rc, &cr)
+		obj, err := sw.UpdateStatus("default", "foo", func(obj runtime.Object) error {
+			obj.(*Widget).Labels = map[string]string{"ready": "true"}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if obj.(*Widget).Labels["ready"] != "true" {
+			t.Errorf("Expected the updated object to be returned")
+		}
+
+		exp := []string{
+			"GET /apis/kubekit/v1test1/namespaces/default/widgets/foo",
+			"PUT /apis/kubekit/v1test1/namespaces/default/widgets/foo/status",
+			"GET /apis/kubekit/v1test1/namespaces/default/widgets/foo",
+			"PUT /apis/kubekit/v1test1/namespaces/default/widgets/foo/status",
+		}
+		if len(requests) != len(exp) {
+			t.Fatalf("Expected requests %v, got %v", exp, requests)
+		}
+
+		for i := range exp {
+			if requests[i] != exp[i] {
+				t.Errorf("Expected request '%s', got '%s'", exp[i], requests[i])
+			}
+		}
+	})
+
+	t.Run("patch status", func(t *testing.T) {
+		requests = nil
+		conflicts = 0
+
+		sw := kubekit.NewStatusWriter(rc, &cr)
+		if _, err := sw.PatchStatus("default", "foo", types.MergePatchType, []byte(`{"metadata":{}}`)); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		if exp := "PATCH /apis/kubekit/v1test1/namespaces/default/widgets/foo/status"; requests[0] != exp {
+			t.Errorf("Expected request '%s', got '%s'", exp, requests[0])
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		sw := kubekit.NewStatusWriter(rc, &cr)
+		obj, err := sw.UpdateStatus("default", "broken", func(obj runtime.Object) error { return nil })
+		if !apierrors.IsInternalError(err) || obj != nil {
+			t.Errorf("Expected an error without an object, got %v and %v", err, obj)
+		}
+
+		obj, err = sw.PatchStatus("default", "broken", types.MergePatchType, []byte(`{"metadata":{}}`))
+		if !apierrors.IsInternalError(err) || obj != nil {
+			t.Errorf("Expected an error without an object, got %v and %v", err, obj)
+		}
+	})
+}
@@ -1,53 +1,432 @@
 package kubekit
 
 import (
+	"context"
+	"sort"
+	"sync"
 	"time"
 
+	kerrors "github.com/jelmersnoeck/kubekit/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 )
 
-// ResyncPeriod is the delay between resync actions from the controller. This
-// can be overwritten at package level to define the ResyncPeriod for the
-// controller.
+// ResyncPeriod is the default delay between resync actions from the
+// controller. This can be overwritten at package level to define the
+// ResyncPeriod for all Watchers, or per Watcher with WithResyncPeriod.
 var ResyncPeriod = 5 * time.Second
 
 // Watcher represents a CRD Watcher Object. It knows enough details about a CRD
 // to be able to create a controller and watch for changes.
+// A Watcher can watch multiple namespaces, in which case it runs a separate
+// informer for every namespace. All these informers share the same handler and
+// the same cache. The handler is called by one informer at a time.
 type Watcher struct {
-	cg        cache.Getter
-	namespace string
-	resource  *CustomResource
-	handler   cache.ResourceEventHandler
+	cg       cache.Getter
+	resource *CustomResource
+	handler  cache.ResourceEventHandler
+
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+	tweaks        []func(*metav1.ListOptions)
+	resyncPeriod  time.Duration
+
+	indexer   cache.Indexer
+	processMu sync.Mutex
+
+	mu         sync.Mutex
+	done       <-chan struct{}
+	namespaces map[string]*namespaceInformer
+	purging    map[string]chan struct{}
+}
+
+// namespaceInformer keeps track of the informer for a single namespace. The
+// informer is nil as long as the Watcher isn't running. The done channel is
+// closed once the informer has stopped.
+type namespaceInformer struct {
+	controller cache.Controller
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// WatcherOption represents a function that can be used to configure a Watcher.
+type WatcherOption func(w *Watcher)
+
+// WithLabelSelector limits the watched objects to the objects matching the
+// given label selector.
+func WithLabelSelector(s labels.Selector) WatcherOption {
+	return func(w *Watcher) {
+		w.labelSelector = s
+	}
+}
+
+// WithFieldSelector limits the watched objects to the objects matching the
+// given field selector, for example `fields.OneTermEqualSelector("metadata.name", "foo")`.
+func WithFieldSelector(s fields.Selector) WatcherOption {
+	return func(w *Watcher) {
+		w.fieldSelector = s
+	}
+}
+
+// WithListOptions allows modifying the ListOptions which are used for both
+// listing and watching the objects. The function is called after the label
+// and field selectors have been applied.
+func WithListOptions(fn func(*metav1.ListOptions)) WatcherOption {
+	return func(w *Watcher) {
+		w.tweaks = append(w.tweaks, fn)
+	}
+}
+
+// WithResyncPeriod sets the delay between resync actions for this Watcher. A
+// period of 0 disables resyncing. Defaults to ResyncPeriod.
+func WithResyncPeriod(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.resyncPeriod = d
+	}
+}
+
+// WithNamespaces configures the Watcher to watch the given set of namespaces
+// as well as the namespace passed to NewWatcher. Pass an empty namespace to
+// NewWatcher to only watch the given namespaces. This is useful when the
+// controller is not allowed to watch all namespaces.
+// An empty namespace watches all namespaces, it can't be combined with other
+// namespaces.
+func WithNamespaces(namespaces ...string) WatcherOption {
+	return func(w *Watcher) {
+		for _, ns := range namespaces {
+			w.namespaces[ns] = nil
+		}
+	}
 }
 
 // NewWatcher returns a new watcher that can be used to watch in a given
-// namespace. If namespace is an empty string, all namespaces will be watched.
-func NewWatcher(cg cache.Getter, namespace string, resource *CustomResource, handler cache.ResourceEventHandler) *Watcher {
-	return &Watcher{
-		cg:        cg,
-		namespace: namespace,
-		resource:  resource,
-		handler:   handler,
+// namespace. If namespace is an empty string, all namespaces will be watched,
+// unless namespaces are configured with WithNamespaces.
+// NewWatcher panics with errors.ErrOverlappingNamespaces when all namespaces
+// are watched together with specific namespaces.
+func NewWatcher(cg cache.Getter, namespace string, resource *CustomResource, handler cache.ResourceEventHandler, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		cg:            cg,
+		resource:      resource,
+		handler:       handler,
+		labelSelector: labels.Everything(),
+		fieldSelector: fields.Everything(),
+		resyncPeriod:  ResyncPeriod,
+		namespaces:    map[string]*namespaceInformer{},
+		purging:       map[string]chan struct{}{},
+		indexer: cache.NewIndexer(
+			cache.DeletionHandlingMetaNamespaceKeyFunc,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		),
+	}
+
+	for _, opt := range opts {
+		opt(w)
 	}
+
+	if namespace != "" || len(w.namespaces) == 0 {
+		w.namespaces[namespace] = nil
+	}
+
+	// the informer for all namespaces would share the cache with the informers
+	// of the specific namespaces, relisting either one would remove the
+	// objects of the other.
+	if w.overlaps() {
+		panic(kerrors.ErrOverlappingNamespaces)
+	}
+
+	return w
 }
 
 // Run starts watching the CRDs associated with the Watcher through a
-// Kubernetes CacheController.
+// Kubernetes CacheController for every configured namespace.
 func (w *Watcher) Run(done <-chan struct{}) {
-	source := cache.NewListWatchFromClient(
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.done = done
+	for ns := range w.namespaces {
+		w.start(ns)
+	}
+}
+
+// HasSynced returns true when the informers of all the watched namespaces have
+// synced their initial list of objects into the cache. It returns false when
+// the Watcher isn't running.
+func (w *Watcher) HasSynced() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done == nil {
+		return false
+	}
+
+	for _, informer := range w.namespaces {
+		if informer == nil || !informer.controller.HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WaitForCacheSync blocks until the cache of the Watcher has synced or the
+// context is done, in which case the context error is returned.
+func (w *Watcher) WaitForCacheSync(ctx context.Context) error {
+	err := wait.PollUntil(100*time.Millisecond, func() (bool, error) {
+		return w.HasSynced(), nil
+	}, ctx.Done())
+
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// Indexer returns the cache which is shared between the informers of all the
+// watched namespaces. Objects in the cache should be treated as read-only.
+func (w *Watcher) Indexer() cache.Indexer {
+	return w.indexer
+}
+
+// Lister returns a Lister which reads objects from the cache of the Watcher,
+// so reconcilers don't have to query the API server.
+func (w *Watcher) Lister() *Lister {
+	return NewLister(w.indexer, w.resource)
+}
+
+// Namespaces returns the sorted list of namespaces the Watcher is watching.
+func (w *Watcher) Namespaces() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	namespaces := make([]string, 0, len(w.namespaces))
+	for ns := range w.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+// AddNamespace adds a namespace to the set of watched namespaces. If the
+// Watcher is already running, the informer for this namespace is started
+// immediately. Adding all namespaces while specific namespaces are watched, or
+// the other way around, returns errors.ErrOverlappingNamespaces.
+func (w *Watcher) AddNamespace(namespace string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.namespaces[namespace]; ok {
+		return nil
+	}
+
+	w.namespaces[namespace] = nil
+	if w.overlaps() {
+		delete(w.namespaces, namespace)
+		return kerrors.ErrOverlappingNamespaces
+	}
+
+	if w.done != nil {
+		w.start(namespace)
+	}
+
+	return nil
+}
+
+// overlaps returns wether or not all namespaces are watched together with
+// specific namespaces.
+func (w *Watcher) overlaps() bool {
+	_, all := w.namespaces[metav1.NamespaceAll]
+	return all && len(w.namespaces) > 1
+}
+
+// RemoveNamespace stops watching the given namespace. The informer of the
+// namespace is stopped and RemoveNamespace returns without waiting for it, so
+// it can be called from the handler. Once the informer has stopped, all the
+// objects of this namespace are removed from the cache without notifying the
+// handler.
+func (w *Watcher) RemoveNamespace(namespace string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	informer, ok := w.namespaces[namespace]
+	delete(w.namespaces, namespace)
+	if !ok || informer == nil {
+		return
+	}
+
+	close(informer.stop)
+
+	// when the namespace is added again before the cache is purged, its new
+	// informer waits for the purge so its objects don't get removed.
+	previous := w.purging[namespace]
+	purged := make(chan struct{})
+	w.purging[namespace] = purged
+
+	go w.purge(namespace, informer, previous, purged)
+}
+
+// purge removes the objects of the namespace from the cache once its informer
+// and any previous purge of the namespace have finished.
+func (w *Watcher) purge(namespace string, informer *namespaceInformer, previous <-chan struct{}, purged chan struct{}) {
+	defer close(purged)
+
+	<-informer.done
+	if previous != nil {
+		<-previous
+	}
+
+	store := &namespacedStore{indexer: w.indexer, namespace: namespace}
+	for _, key := range store.ListKeys() {
+		if obj, exists, err := w.indexer.GetByKey(key); err == nil && exists {
+			w.indexer.Delete(obj)
+		}
+	}
+
+	w.mu.Lock()
+	if w.purging[namespace] == purged {
+		delete(w.purging, namespace)
+	}
+	w.mu.Unlock()
+}
+
+// start runs the informer for the given namespace until either the Watcher is
+// stopped or the namespace is removed. The caller must hold the lock.
+func (w *Watcher) start(namespace string) {
+	informer := &namespaceInformer{
+		controller: w.newController(namespace),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	w.namespaces[namespace] = informer
+
+	stop := make(chan struct{})
+	go func(done <-chan struct{}) {
+		select {
+		case <-done:
+		case <-informer.stop:
+		}
+		close(stop)
+	}(w.done)
+
+	// purging all namespaces removes the objects of every namespace, wait
+	// for those purges as well.
+	var purges []chan struct{}
+	for ns, purged := range w.purging {
+		if ns == namespace || ns == metav1.NamespaceAll || namespace == metav1.NamespaceAll {
+			purges = append(purges, purged)
+		}
+	}
+
+	go func() {
+		defer close(informer.done)
+
+		for _, purged := range purges {
+			select {
+			case <-purged:
+			case <-stop:
+				return
+			}
+		}
+
+		informer.controller.Run(stop)
+	}()
+}
+
+// newController sets up a controller which keeps the shared indexer in sync
+// for the given namespace. This mimics cache.NewIndexerInformer, but limits
+// the DeltaFIFO to the objects of its own namespace so that relisting one
+// namespace doesn't delete the objects of another namespace.
+func (w *Watcher) newController(namespace string) cache.Controller {
+	source := cache.NewFilteredListWatchFromClient(
 		w.cg,
-		w.resource.Plural,
-		w.namespace,
-		fields.Everything(),
+		w.resource.GetPlural(),
+		namespace,
+		w.tweakListOptions,
 	)
 
-	_, controller := cache.NewInformer(
-		source,
-		w.resource.Object,
-		ResyncPeriod,
-		w.handler,
+	fifo := cache.NewDeltaFIFO(
+		cache.MetaNamespaceKeyFunc,
+		&namespacedStore{indexer: w.indexer, namespace: namespace},
 	)
 
-	go controller.Run(done)
+	return cache.New(&cache.Config{
+		Queue:            fifo,
+		ListerWatcher:    source,
+		ObjectType:       w.resource.Object,
+		FullResyncPeriod: w.resyncPeriod,
+		RetryOnError:     false,
+		Process:          w.process,
+	})
+}
+
+func (w *Watcher) process(obj interface{}) error {
+	// the informers of all namespaces share the indexer and handler, process
+	// their changes one at a time.
+	w.processMu.Lock()
+	defer w.processMu.Unlock()
+
+	// from oldest to newest
+	for _, d := range obj.(cache.Deltas) {
+		switch d.Type {
+		case cache.Sync, cache.Added, cache.Updated:
+			if old, exists, err := w.indexer.Get(d.Object); err == nil && exists {
+				if err := w.indexer.Update(d.Object); err != nil {
+					return err
+				}
+				w.handler.OnUpdate(old, d.Object)
+			} else {
+				if err := w.indexer.Add(d.Object); err != nil {
+					return err
+				}
+				w.handler.OnAdd(d.Object)
+			}
+		case cache.Deleted:
+			if err := w.indexer.Delete(d.Object); err != nil {
+				return err
+			}
+			w.handler.OnDelete(d.Object)
+		}
+	}
+
+	return nil
+}
+
+func (w *Watcher) tweakListOptions(opts *metav1.ListOptions) {
+	opts.LabelSelector = w.labelSelector.String()
+	opts.FieldSelector = w.fieldSelector.String()
+
+	for _, tweak := range w.tweaks {
+		tweak(opts)
+	}
+}
+
+// namespacedStore exposes the keys of a single namespace of an indexer. An
+// empty namespace exposes all keys.
+type namespacedStore struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+func (s *namespacedStore) ListKeys() []string {
+	if s.namespace == metav1.NamespaceAll {
+		return s.indexer.ListKeys()
+	}
+
+	keys, err := s.indexer.IndexKeys(cache.NamespaceIndex, s.namespace)
+	if err != nil {
+		return nil
+	}
+
+	return keys
+}
+
+func (s *namespacedStore) GetByKey(key string) (interface{}, bool, error) {
+	return s.indexer.GetByKey(key)
 }
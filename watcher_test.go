@@ -0,0 +1,406 @@
+package kubekit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jelmersnoeck/kubekit"
+	"github.com/jelmersnoeck/kubekit/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+var widgetGroupVersion = schema.GroupVersion{Group: "kubekit", Version: "v1test1"}
+
+var widgetResource = &kubekit.CustomResource{
+	Group:   widgetGroupVersion.Group,
+	Version: widgetGroupVersion.Version,
+	Object:  &Widget{},
+}
+
+type WidgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Widget `json:"items"`
+}
+
+func (l *WidgetList) DeepCopyObject() runtime.Object {
+	cp := *l
+	cp.Items = make([]Widget, len(l.Items))
+	for i := range l.Items {
+		cp.Items[i] = *l.Items[i].DeepCopyObject().(*Widget)
+	}
+	return &cp
+}
+
+func addWidgetTypes(s *runtime.Scheme) error {
+	s.AddKnownTypes(widgetGroupVersion, &Widget{}, &WidgetList{})
+	metav1.AddToGroupVersion(s, widgetGroupVersion)
+	return nil
+}
+
+// fakeAPIServer serves list and watch requests for Widgets and records the
+// queries it received. Every recorded request is signalled on requested.
+type fakeAPIServer struct {
+	*httptest.Server
+
+	sync.Mutex
+	widgets   map[string][]Widget
+	queries   []url.Values
+	paths     []string
+	requested chan struct{}
+}
+
+func newFakeAPIServer(t *testing.T, widgets map[string][]Widget) (*fakeAPIServer, *rest.RESTClient) {
+	s := &fakeAPIServer{widgets: widgets, requested: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	rc, err := kubekit.RESTClient(&rest.Config{Host: s.URL}, &widgetGroupVersion, []kubekit.SchemeBuilder{addWidgetTypes})
+	if err != nil {
+		t.Fatalf("Could not create RESTClient: %s", err)
+	}
+
+	return s, rc
+}
+
+func (s *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.queries = append(s.queries, r.URL.Query())
+	s.paths = append(s.paths, r.URL.Path)
+	s.Unlock()
+
+	select {
+	case s.requested <- struct{}{}:
+	default:
+	}
+
+	if r.URL.Query().Get("watch") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		return
+	}
+
+	// /apis/kubekit/v1test1[/namespaces/<ns>]/widgets
+	namespace := ""
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 4 && parts[3] == "namespaces" {
+		namespace = parts[4]
+	}
+
+	s.Lock()
+	list := &WidgetList{
+		TypeMeta: metav1.TypeMeta{Kind: "WidgetList", APIVersion: widgetGroupVersion.String()},
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+	}
+	for ns, widgets := range s.widgets {
+		if namespace == "" || ns == namespace {
+			list.Items = append(list.Items, widgets...)
+		}
+	}
+	s.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (s *fakeAPIServer) recorded() ([]string, []url.Values) {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string{}, s.paths...), append([]url.Values{}, s.queries...)
+}
+
+func TestWatcher_Selectors(t *testing.T) {
+	srv, rc := newFakeAPIServer(t, nil)
+	defer srv.Close()
+
+	w := kubekit.NewWatcher(rc, "default", widgetResource, cache.ResourceEventHandlerFuncs{},
+		kubekit.WithLabelSelector(labels.SelectorFromSet(labels.Set{"shard": "a"})),
+		kubekit.WithFieldSelector(fields.OneTermEqualSelector("metadata.name", "foo")),
+		kubekit.WithListOptions(func(opts *metav1.ListOptions) {
+			opts.Limit = 10
+		}),
+	)
+
+	done := make(chan struct{})
+	defer close(done)
+	w.Run(done)
+
+	select {
+	case <-srv.requested:
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the list request")
+	}
+
+	paths, queries := srv.recorded()
+	if len(queries) == 0 {
+		t.Fatalf("Expected the API server to receive a request")
+	}
+
+	if exp := "/apis/kubekit/v1test1/namespaces/default/widgets"; paths[0] != exp {
+		t.Errorf("Expected path '%s', got '%s'", exp, paths[0])
+	}
+
+	q := queries[0]
+	if s := q.Get("labelSelector"); s != "shard=a" {
+		t.Errorf("Expected labelSelector 'shard=a', got '%s'", s)
+	}
+
+	if s := q.Get("fieldSelector"); s != "metadata.name=foo" {
+		t.Errorf("Expected fieldSelector 'metadata.name=foo', got '%s'", s)
+	}
+
+	if s := q.Get("limit"); s != "10" {
+		t.Errorf("Expected limit '10', got '%s'", s)
+	}
+}
+
+type addRecorder struct {
+	cache.ResourceEventHandlerFuncs
+	added chan string
+}
+
+func newAddRecorder() *addRecorder {
+	r := &addRecorder{added: make(chan string, 10)}
+	r.AddFunc = func(obj interface{}) {
+		key, _ := cache.MetaNamespaceKeyFunc(obj)
+		r.added <- key
+	}
+	return r
+}
+
+func (r *addRecorder) expect(t *testing.T, keys ...string) {
+	exp := map[string]bool{}
+	for _, k := range keys {
+		exp[k] = true
+	}
+
+	for range keys {
+		select {
+		case key := <-r.added:
+			if !exp[key] {
+				t.Errorf("Did not expect '%s' to be added", key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %v to be added", keys)
+		}
+	}
+}
+
+func TestWatcher_Namespaces(t *testing.T) {
+	widget := func(ns string) Widget {
+		return Widget{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "foo", ResourceVersion: "1"}}
+	}
+
+	srv, rc := newFakeAPIServer(t, map[string][]Widget{
+		"a": {widget("a")},
+		"b": {widget("b")},
+		"c": {widget("c")},
+	})
+	defer srv.Close()
+
+	r := newAddRecorder()
+	w := kubekit.NewWatcher(rc, "", widgetResource, r, kubekit.WithNamespaces("a", "b"))
+	if ns := w.Namespaces(); len(ns) != 2 || ns[0] != "a" || ns[1] != "b" {
+		t.Errorf("Expected namespaces [a b], got %v", ns)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	w.Run(done)
+	r.expect(t, "a/foo", "b/foo")
+
+	w.RemoveNamespace("a")
+	if err := w.AddNamespace("c"); err != nil {
+		t.Fatalf("Expected no error adding a namespace, got %s", err)
+	}
+	r.expect(t, "c/foo")
+
+	if err := w.AddNamespace(""); !errors.IsOverlappingNamespaces(err) {
+		t.Errorf("Expected ErrOverlappingNamespaces adding all namespaces, got %v", err)
+	}
+
+	if ns := w.Namespaces(); len(ns) != 2 || ns[0] != "b" || ns[1] != "c" {
+		t.Errorf("Expected namespaces [b c], got %v", ns)
+	}
+}
+
+func TestNewWatcher_Namespaces(t *testing.T) {
+	tcs := []struct {
+		name       string
+		namespace  string
+		namespaces []string
+		expected   []string
+	}{
+		{"all namespaces", "", nil, []string{""}},
+		{"single namespace", "a", nil, []string{"a"}},
+		{"only configured namespaces", "", []string{"a", "b"}, []string{"a", "b"}},
+		{"combined namespaces", "a", []string{"b"}, []string{"a", "b"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := kubekit.NewWatcher(nil, tc.namespace, widgetResource, cache.ResourceEventHandlerFuncs{}, kubekit.WithNamespaces(tc.namespaces...))
+			if ns := w.Namespaces(); !reflect.DeepEqual(ns, tc.expected) {
+				t.Errorf("Expected namespaces %v, got %v", tc.expected, ns)
+			}
+		})
+	}
+
+	t.Run("overlapping namespaces", func(t *testing.T) {
+		defer func() {
+			if err, _ := recover().(error); !errors.IsOverlappingNamespaces(err) {
+				t.Errorf("Expected a panic with ErrOverlappingNamespaces, got %v", err)
+			}
+		}()
+
+		kubekit.NewWatcher(nil, "a", widgetResource, cache.ResourceEventHandlerFuncs{}, kubekit.WithNamespaces(""))
+	})
+}
+
+func TestWatcher_SerialHandler(t *testing.T) {
+	widgets := map[string][]Widget{}
+	for _, ns := range []string{"a", "b", "c"} {
+		widgets[ns] = []Widget{{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "foo", ResourceVersion: "1"}}}
+	}
+
+	srv, rc := newFakeAPIServer(t, widgets)
+	defer srv.Close()
+
+	var active, concurrent int32
+	r := newAddRecorder()
+	add := r.AddFunc
+	r.AddFunc = func(obj interface{}) {
+		if atomic.AddInt32(&active, 1) > 1 {
+			atomic.StoreInt32(&concurrent, 1)
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		add(obj)
+	}
+
+	w := kubekit.NewWatcher(rc, "", widgetResource, r, kubekit.WithNamespaces("a", "b", "c"))
+
+	done := make(chan struct{})
+	defer close(done)
+	w.Run(done)
+	r.expect(t, "a/foo", "b/foo", "c/foo")
+
+	if atomic.LoadInt32(&concurrent) != 0 {
+		t.Errorf("Expected the handler not to be called concurrently")
+	}
+}
+
+func TestWatcher_Cache(t *testing.T) {
+	srv, rc := newFakeAPIServer(t, map[string][]Widget{
+		"a": {{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo", ResourceVersion: "1"}}},
+		"b": {{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "bar", ResourceVersion: "1"}}},
+	})
+	defer srv.Close()
+
+	w := kubekit.NewWatcher(rc, "", widgetResource, cache.ResourceEventHandlerFuncs{},
+		kubekit.WithNamespaces("a", "b"),
+		kubekit.WithResyncPeriod(0),
+	)
+	if w.HasSynced() {
+		t.Errorf("Expected Watcher not to be synced before running")
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	w.Run(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.WaitForCacheSync(ctx); err != nil {
+		t.Fatalf("Expected cache to sync, got %s", err)
+	}
+
+	lister := w.Lister()
+	objs, err := lister.List("", labels.Everything())
+	if err != nil {
+		t.Fatalf("Expected no error listing, got %s", err)
+	}
+
+	if len(objs) != 2 {
+		t.Errorf("Expected 2 objects in the cache, got %d", len(objs))
+	}
+
+	if _, err := lister.Get("b", "bar"); err != nil {
+		t.Errorf("Expected to get b/bar, got %s", err)
+	}
+
+	if _, err := lister.Get("a", "bar"); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected NotFound error for a/bar, got %v", err)
+	}
+
+	w.RemoveNamespace("a")
+	expectKeys(t, w.Indexer(), "b/bar")
+}
+
+func TestWatcher_RemoveNamespaceFromHandler(t *testing.T) {
+	srv, rc := newFakeAPIServer(t, map[string][]Widget{
+		"a": {{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "foo", ResourceVersion: "1"}}},
+		"b": {{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "foo", ResourceVersion: "1"}}},
+	})
+	defer srv.Close()
+
+	var w *kubekit.Watcher
+	r := newAddRecorder()
+	add := r.AddFunc
+	r.AddFunc = func(obj interface{}) {
+		if obj.(*Widget).Namespace == "a" {
+			w.RemoveNamespace("a")
+		}
+		add(obj)
+	}
+
+	w = kubekit.NewWatcher(rc, "", widgetResource, r, kubekit.WithNamespaces("a", "b"))
+
+	done := make(chan struct{})
+	defer close(done)
+	w.Run(done)
+	r.expect(t, "a/foo", "b/foo")
+
+	if ns := w.Namespaces(); len(ns) != 1 || ns[0] != "b" {
+		t.Errorf("Expected namespaces [b], got %v", ns)
+	}
+
+	expectKeys(t, w.Indexer(), "b/foo")
+}
+
+// expectKeys waits for the indexer to only contain the given keys, objects of
+// removed namespaces are purged in the background.
+func expectKeys(t *testing.T, indexer cache.Indexer, keys ...string) {
+	var found []string
+	err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		found = indexer.ListKeys()
+		sort.Strings(found)
+		return reflect.DeepEqual(found, keys), nil
+	})
+	if err != nil {
+		t.Errorf("Expected keys %v in the cache, got %v", keys, found)
+	}
+}